@@ -26,6 +26,23 @@ func updateSchema() error {
 			}
 		}
 	}
+	// a dependency maybe the name of a task, or an object with a condition
+	if dependency, ok := s.Definitions["Dependency"]; ok {
+		s.Definitions["Dependency"] = &jsonschema.Schema{
+			Title:       dependency.Title,
+			Description: dependency.Description,
+			OneOf:       []*jsonschema.Schema{{Type: "string"}, dependency},
+		}
+	}
+	// a status code maybe a number, or a string with a range or a class
+	if statusCodes, ok := s.Definitions["StatusCodes"]; ok {
+		code := &jsonschema.Schema{OneOf: []*jsonschema.Schema{{Type: "integer"}, {Type: "string"}}}
+		s.Definitions["StatusCodes"] = &jsonschema.Schema{
+			Title:       statusCodes.Title,
+			Description: statusCodes.Description,
+			OneOf:       []*jsonschema.Schema{code, {Type: "array", Items: code}},
+		}
+	}
 	data, _ := json.MarshalIndent(s, "", "  ")
 	if err := os.WriteFile("schema/workflow.schema.json", data, 0o777); err != nil {
 		return fmt.Errorf("failed to write schema/workflow.schema.json: %w", err)
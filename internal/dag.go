@@ -1,5 +1,13 @@
 package internal
 
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
 // describe a directed acyclic graph
 
 type DAG[Node any] struct {
@@ -50,3 +58,178 @@ func (d *DAG[Node]) Subgraph(nodeNames []string) map[string]bool {
 	}
 	return visited
 }
+
+// Validate checks that every edge is between known nodes, that no node depends on itself, and that there are no cycles.
+// All problems are reported, not just the first.
+func (d *DAG[Node]) Validate() error {
+	var errs []error
+	for _, name := range d.names() {
+		for _, parent := range d.Parents[name] {
+			if parent == name {
+				errs = append(errs, fmt.Errorf("%q depends on itself", name))
+			} else if _, ok := d.Nodes[parent]; !ok {
+				errs = append(errs, fmt.Errorf("%q depends on unknown %q", name, parent))
+			}
+		}
+	}
+	for _, cycle := range d.Cycles() {
+		errs = append(errs, fmt.Errorf("dependency cycle: %s", strings.Join(cycle, " -> ")))
+	}
+	return errors.Join(errs...)
+}
+
+// Cycles returns every cycle in the graph as a path that starts and ends with the same node, e.g. [a b a]. Each cycle
+// starts with its first node in name order, and is reported once. Self-edges are not reported as cycles.
+//
+// This is Johnson's algorithm: for each node in turn, it finds the cycles through it in the strongly connected
+// component it is in, of the graph of it and the nodes after it.
+func (d *DAG[Node]) Cycles() [][]string {
+	var cycles [][]string
+	names := d.names()
+	for i, start := range names {
+		// only the nodes after the start, so each cycle is found from its first node
+		after := make(map[string]bool)
+		for _, name := range names[i:] {
+			after[name] = true
+		}
+		component := d.component(start, after)
+		blocked := make(map[string]bool)
+		blockedBy := make(map[string]map[string]bool)
+		var unblock func(string)
+		unblock = func(name string) {
+			blocked[name] = false
+			for other := range blockedBy[name] {
+				delete(blockedBy[name], other)
+				if blocked[other] {
+					unblock(other)
+				}
+			}
+		}
+		var stack []string
+		var circuit func(string) bool
+		circuit = func(name string) bool {
+			found := false
+			stack = append(stack, name)
+			blocked[name] = true
+			for _, child := range d.children(name) {
+				if !component[child] {
+					continue
+				}
+				if child == start {
+					cycles = append(cycles, append(append([]string{}, stack...), start))
+					found = true
+				} else if !blocked[child] && circuit(child) {
+					found = true
+				}
+			}
+			if found {
+				unblock(name)
+			} else {
+				for _, child := range d.children(name) {
+					if !component[child] {
+						continue
+					}
+					if blockedBy[child] == nil {
+						blockedBy[child] = make(map[string]bool)
+					}
+					blockedBy[child][name] = true
+				}
+			}
+			stack = stack[:len(stack)-1]
+			return found
+		}
+		circuit(start)
+	}
+	return cycles
+}
+
+// component returns the strongly connected component of the node, in the graph of the nodes in the set, i.e. the nodes
+// that can both be reached from it, and reach it
+func (d *DAG[Node]) component(name string, set map[string]bool) map[string]bool {
+	reach := func(next func(string) []string) map[string]bool {
+		reached := map[string]bool{name: true}
+		queue := []string{name}
+		for len(queue) > 0 {
+			x := queue[0]
+			queue = queue[1:]
+			for _, y := range next(x) {
+				if set[y] && !reached[y] {
+					reached[y] = true
+					queue = append(queue, y)
+				}
+			}
+		}
+		return reached
+	}
+	forward := reach(func(x string) []string { return d.Children[x] })
+	backward := reach(func(x string) []string { return d.Parents[x] })
+	component := make(map[string]bool)
+	for x := range forward {
+		if backward[x] {
+			component[x] = true
+		}
+	}
+	return component
+}
+
+// children returns the known children of the node, sorted and without duplicates or self-edges, so that results are
+// stable
+func (d *DAG[Node]) children(name string) []string {
+	var children []string
+	for _, child := range d.Children[name] {
+		if _, ok := d.Nodes[child]; ok && child != name {
+			children = append(children, child)
+		}
+	}
+	sort.Strings(children)
+	return slices.Compact(children)
+}
+
+// ReverseTiers returns the nodes in reverse topological order, in tiers: the first tier is the nodes without children,
+// and each node is in the tier after the last of its children.
+func (d *DAG[Node]) ReverseTiers() [][]string {
+	remaining := make(map[string]int)
+	for _, name := range d.names() {
+		for _, child := range d.Children[name] {
+			if _, ok := d.Nodes[child]; ok {
+				remaining[name]++
+			}
+		}
+	}
+	var tiers [][]string
+	placed := make(map[string]bool)
+	for len(placed) < len(d.Nodes) {
+		var tier []string
+		for _, name := range d.names() {
+			if !placed[name] && remaining[name] == 0 {
+				tier = append(tier, name)
+			}
+		}
+		// there is a cycle, so the rest cannot be ordered
+		if len(tier) == 0 {
+			for _, name := range d.names() {
+				if !placed[name] {
+					tier = append(tier, name)
+				}
+			}
+		}
+		for _, name := range tier {
+			placed[name] = true
+			for _, parent := range d.Parents[name] {
+				remaining[parent]--
+			}
+		}
+		tiers = append(tiers, tier)
+	}
+	return tiers
+}
+
+// names returns the names of the nodes, sorted so that results are stable
+func (d *DAG[Node]) names() []string {
+	var names []string
+	for name := range d.Nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
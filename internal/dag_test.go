@@ -2,6 +2,8 @@ package internal
 
 import (
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestDAG_Subgraph(t *testing.T) {
@@ -21,3 +23,77 @@ func TestDAG_Subgraph(t *testing.T) {
 		t.Fatalf("expected c in subgraph")
 	}
 }
+
+func TestDAG_Validate(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		d := NewDAG[int]("")
+		d.AddNode("a", 1)
+		d.AddNode("b", 2)
+		d.AddEdge("a", "b")
+		assert.NoError(t, d.Validate())
+	})
+	t.Run("Unknown dependency", func(t *testing.T) {
+		d := NewDAG[int]("")
+		d.AddNode("a", 1)
+		d.AddEdge("b", "a")
+		assert.EqualError(t, d.Validate(), `"a" depends on unknown "b"`)
+	})
+	t.Run("Self dependency", func(t *testing.T) {
+		d := NewDAG[int]("")
+		d.AddNode("a", 1)
+		d.AddEdge("a", "a")
+		assert.EqualError(t, d.Validate(), `"a" depends on itself`)
+	})
+	t.Run("Cycle", func(t *testing.T) {
+		d := NewDAG[int]("")
+		d.AddNode("a", 1)
+		d.AddNode("b", 2)
+		d.AddNode("c", 3)
+		d.AddEdge("a", "b")
+		d.AddEdge("b", "c")
+		d.AddEdge("c", "a")
+		assert.EqualError(t, d.Validate(), "dependency cycle: a -> b -> c -> a")
+	})
+	t.Run("Overlapping cycles", func(t *testing.T) {
+		d := NewDAG[int]("")
+		d.AddNode("a", 1)
+		d.AddNode("b", 2)
+		d.AddNode("c", 3)
+		d.AddEdge("a", "b")
+		d.AddEdge("b", "a")
+		d.AddEdge("a", "c")
+		d.AddEdge("c", "b")
+		assert.Equal(t, [][]string{{"a", "b", "a"}, {"a", "c", "b", "a"}}, d.Cycles())
+	})
+	t.Run("All problems", func(t *testing.T) {
+		d := NewDAG[int]("")
+		d.AddNode("a", 1)
+		d.AddNode("b", 2)
+		d.AddNode("c", 3)
+		d.AddNode("d", 4)
+		d.AddEdge("a", "b")
+		d.AddEdge("b", "a")
+		d.AddEdge("c", "d")
+		d.AddEdge("d", "c")
+		d.AddEdge("d", "d")
+		d.AddEdge("x", "a")
+		assert.EqualError(t, d.Validate(), `"a" depends on unknown "x"
+"d" depends on itself
+dependency cycle: a -> b -> a
+dependency cycle: c -> d -> c`)
+	})
+}
+
+func TestDAG_ReverseTiers(t *testing.T) {
+	d := NewDAG[int]("")
+	d.AddNode("db", 1)
+	d.AddNode("cache", 2)
+	d.AddNode("api", 3)
+	d.AddNode("web", 4)
+	d.AddNode("docs", 5)
+	d.AddEdge("db", "api")
+	d.AddEdge("cache", "api")
+	d.AddEdge("api", "web")
+	d.AddEdge("db", "web")
+	assert.Equal(t, [][]string{{"docs", "web"}, {"api"}, {"cache", "db"}}, d.ReverseTiers())
+}
@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/kitproj/kit/internal/types"
+)
+
+// hooks are small actions, so they are stopped if they run for longer than this
+const hookTimeout = time.Minute
+
+// startHook runs the hook in its own goroutine, added to the wait group, so that we wait for it before we exit, e.g.
+// so diagnostics are collected for a failed job, even though it causes us to exit
+func startHook(wg *sync.WaitGroup, spec types.Spec, node TaskNode, name string, hook *types.ExecAction, logger *log.Logger) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		logger.Printf("running %s hook\n", name)
+		if err := runHook(context.Background(), spec, node, hook, logger.Writer()); err != nil {
+			logger.Printf("%s hook failed: %v\n", name, err)
+		}
+	}()
+}
+
+// runHook runs the hook's command on the host, in the task's working directory and environment, with the name of the
+// task, its phase and message in KIT_TASK, KIT_PHASE and KIT_MESSAGE, writing its output to out and the task's log file
+func runHook(ctx context.Context, spec types.Spec, node TaskNode, hook *types.ExecAction, out io.Writer) error {
+	if len(hook.Command) == 0 {
+		return fmt.Errorf("no command")
+	}
+	environ, err := types.Environ(spec, node.task)
+	if err != nil {
+		return fmt.Errorf("failed to get environ: %w", err)
+	}
+	// the task may be writing to its log file too, so we must append to it
+	file, err := os.OpenFile(node.logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o666)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer file.Close()
+	ctx, cancel := context.WithTimeout(ctx, hookTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, hook.Command[0], hook.Command[1:]...)
+	cmd.Dir = node.task.WorkingDir
+	cmd.Env = append(append(environ, os.Environ()...),
+		"KIT_TASK="+node.Name,
+		"KIT_PHASE="+node.Phase,
+		"KIT_MESSAGE="+node.Message,
+	)
+	cmd.Stdout = io.MultiWriter(out, file)
+	cmd.Stderr = cmd.Stdout
+	return cmd.Run()
+}
@@ -0,0 +1,46 @@
+package internal
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+// a lineWriter calls the function for each complete line written to it
+type lineWriter struct {
+	mu sync.Mutex
+	// a partial line, waiting for the rest of it
+	buf []byte
+	f   func(line string)
+}
+
+func newLineWriter(f func(line string)) *lineWriter {
+	return &lineWriter{f: f}
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := strings.TrimSuffix(string(w.buf[:i]), "\r")
+		w.buf = w.buf[i+1:]
+		w.f(line)
+	}
+	return len(p), nil
+}
+
+// Flush calls the function for the partial line, if any, e.g. once the process has exited
+func (w *lineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		line := strings.TrimSuffix(string(w.buf), "\r")
+		w.buf = nil
+		w.f(line)
+	}
+}
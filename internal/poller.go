@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/kitproj/kit/internal/types"
+)
+
+// a poller sends events for changes to matched files, by comparing snapshots of them, for filesystems where fsnotify
+// does not work, e.g. network filesystems
+type poller struct {
+	matcher  *types.Matcher
+	interval time.Duration
+	Events   chan fsnotify.Event
+}
+
+// what we compare to find if a file changed
+type fileStat struct {
+	size    int64
+	modTime time.Time
+	mode    fs.FileMode
+}
+
+func newPoller(matcher *types.Matcher, interval time.Duration) *poller {
+	return &poller{matcher: matcher, interval: interval, Events: make(chan fsnotify.Event, 100)}
+}
+
+// snapshot returns the stats of the matched files
+func (p *poller) snapshot() map[string]fileStat {
+	snapshot := map[string]fileStat{}
+	// if we fail to list the files, e.g. a directory was removed, we just compare the files we found
+	files, _ := p.matcher.Files()
+	for _, file := range files {
+		if stat, err := os.Stat(file); err == nil {
+			snapshot[file] = fileStat{size: stat.Size(), modTime: stat.ModTime(), mode: stat.Mode()}
+		}
+	}
+	return snapshot
+}
+
+// run polls until the context is done
+func (p *poller) run(ctx context.Context) {
+	previous := p.snapshot()
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current := p.snapshot()
+			for name, stat := range current {
+				if before, ok := previous[name]; !ok {
+					p.send(ctx, fsnotify.Event{Name: name, Op: fsnotify.Create})
+				} else if before != stat {
+					p.send(ctx, fsnotify.Event{Name: name, Op: fsnotify.Write})
+				}
+			}
+			for name := range previous {
+				if _, ok := current[name]; !ok {
+					p.send(ctx, fsnotify.Event{Name: name, Op: fsnotify.Remove})
+				}
+			}
+			previous = current
+		}
+	}
+}
+
+func (p *poller) send(ctx context.Context, event fsnotify.Event) {
+	select {
+	case <-ctx.Done():
+	case p.Events <- event:
+	}
+}
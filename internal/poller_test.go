@@ -0,0 +1,49 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoller(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.go")
+
+	task := &types.Task{WorkingDir: dir, Watch: types.Strings{"**/*.go"}}
+	matcher, err := task.Matcher()
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := newPoller(matcher, 10*time.Millisecond)
+	go p.run(ctx)
+
+	next := func(t *testing.T) fsnotify.Event {
+		select {
+		case event := <-p.Events:
+			return event
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for event")
+			return fsnotify.Event{}
+		}
+	}
+
+	// give the poller time to take its first snapshot
+	time.Sleep(50 * time.Millisecond)
+
+	assert.NoError(t, os.WriteFile(file, []byte("package main"), 0644))
+	assert.Equal(t, fsnotify.Event{Name: file, Op: fsnotify.Create}, next(t))
+
+	assert.NoError(t, os.WriteFile(file, []byte("package main\n"), 0644))
+	assert.Equal(t, fsnotify.Event{Name: file, Op: fsnotify.Write}, next(t))
+
+	assert.NoError(t, os.Remove(file))
+	assert.Equal(t, fsnotify.Event{Name: file, Op: fsnotify.Remove}, next(t))
+}
@@ -2,16 +2,79 @@ package internal
 
 import (
 	"context"
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/kitproj/kit/internal/proc"
 	"github.com/kitproj/kit/internal/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"k8s.io/client-go/util/jsonpath"
 )
 
-func probeLoop(ctx context.Context, probe types.Probe, callback func(ok bool, err error)) {
+// startProbe starts the probe, calling the callback when it succeeds or fails, with a message if there is one. Log
+// probes watch the output written to the returned writer.
+func startProbe(ctx context.Context, p proc.Interface, probe types.Probe, callback func(ok bool, message string)) io.Writer {
+	if probe.Log != nil {
+		return logProbe(probe, callback)
+	}
+	go probeLoop(ctx, p, probe, func(ok bool, err error) {
+		if ok {
+			callback(true, "")
+		} else {
+			callback(false, err.Error())
+		}
+	})
+	return io.Discard
+}
+
+// logProbe returns a writer that matches each line written to it against the probe's patterns, the matched line is
+// the message
+func logProbe(probe types.Probe, callback func(ok bool, message string)) io.Writer {
+	var success, failure *regexp.Regexp
+	var err error
+	if probe.Log.Success != "" {
+		success, err = regexp.Compile(probe.Log.Success)
+	}
+	if err == nil && probe.Log.Failure != "" {
+		failure, err = regexp.Compile(probe.Log.Failure)
+	}
+	if err != nil {
+		callback(false, fmt.Sprintf("invalid pattern: %v", err))
+		return io.Discard
+	}
+	successes, failures := 0, 0
+	return newLineWriter(func(line string) {
+		if failure != nil && failure.MatchString(line) {
+			successes = 0
+			failures++
+			if failures == probe.GetFailureThreshold() {
+				callback(false, line)
+			}
+			return
+		}
+		// failures must be consecutive, so any other line ends the streak
+		failures = 0
+		if success != nil && success.MatchString(line) {
+			successes++
+			if successes == probe.GetSuccessThreshold() {
+				callback(true, line)
+			}
+		}
+	})
+}
+
+func probeLoop(ctx context.Context, p proc.Interface, probe types.Probe, callback func(ok bool, err error)) {
 
 	initialDelay := probe.GetInitialDelay()
 	period := probe.GetPeriod()
@@ -22,24 +85,7 @@ func probeLoop(ctx context.Context, probe types.Probe, callback func(ok bool, er
 		case <-ctx.Done():
 			return
 		default:
-			var err error
-			if tcp := probe.TCPSocket; tcp != nil {
-				_, err = net.Dial("tcp", fmt.Sprintf("localhost:%v", tcp.Port))
-			} else if httpGet := probe.HTTPGet; httpGet != nil {
-				err = func() error {
-					resp, err := http.Get(httpGet.GetURL())
-					if err != nil {
-						return fmt.Errorf("failed to get %q: %w", httpGet.GetURL(), err)
-					}
-					if resp.StatusCode >= 300 {
-						data, _ := io.ReadAll(resp.Body)
-						return fmt.Errorf("%s: %q", resp.Status, data)
-					}
-					return nil
-				}()
-			} else {
-				panic(fmt.Errorf("probe not supported"))
-			}
+			err := runProbe(ctx, p, probe)
 
 			if err == nil {
 				failures = 0
@@ -58,3 +104,123 @@ func probeLoop(ctx context.Context, probe types.Probe, callback func(ok bool, er
 		}
 	}
 }
+
+// runProbe runs the probe once, returning an error if it failed
+func runProbe(ctx context.Context, p proc.Interface, probe types.Probe) error {
+	timeout := probe.GetTimeout()
+	if tcp := probe.TCPSocket; tcp != nil {
+		conn, err := net.DialTimeout("tcp", fmt.Sprintf("localhost:%v", tcp.Port), timeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	} else if httpGet := probe.HTTPGet; httpGet != nil {
+		return httpProbe(ctx, *httpGet, timeout)
+	} else if grpcAction := probe.GRPC; grpcAction != nil {
+		return grpcProbe(ctx, *grpcAction, timeout)
+	} else if exec := probe.Exec; exec != nil {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		if err := p.Exec(ctx, exec.Command); err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				return fmt.Errorf("%q timed out after %v", exec.Command.String(), timeout)
+			}
+			return fmt.Errorf("%q failed: %w", exec.Command.String(), err)
+		}
+		return nil
+	}
+	return fmt.Errorf("probe not supported")
+}
+
+// httpProbe gets the URL, and checks the status code and body are as expected
+func httpProbe(ctx context.Context, httpGet types.HTTPGetAction, timeout time.Duration) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, httpGet.GetURL(), nil)
+	if err != nil {
+		return err
+	}
+	for _, h := range httpGet.HTTPHeaders {
+		if strings.EqualFold(h.Name, "Host") {
+			req.Host = h.Value
+		} else {
+			req.Header.Add(h.Name, h.Value)
+		}
+	}
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: httpGet.InsecureSkipVerify},
+			DisableKeepAlives: true,
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to get %q: %w", httpGet.GetURL(), err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read body: %w", err)
+	}
+	if !httpGet.AcceptsStatus(resp.StatusCode) {
+		return fmt.Errorf("%s: %q", resp.Status, data)
+	}
+	if httpGet.Body != "" && !strings.Contains(string(data), httpGet.Body) {
+		return fmt.Errorf("body does not contain %q: %q", httpGet.Body, data)
+	}
+	if httpGet.JSONPath != "" {
+		return jsonPathProbe(httpGet, data)
+	}
+	return nil
+}
+
+// jsonPathProbe checks the JSONPath expression finds a value in the body, and that it is the expected one
+func jsonPathProbe(httpGet types.HTTPGetAction, data []byte) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("failed to parse body as JSON: %w", err)
+	}
+	j := jsonpath.New("probe")
+	if err := j.Parse(httpGet.JSONPath); err != nil {
+		return fmt.Errorf("invalid JSONPath %q: %w", httpGet.JSONPath, err)
+	}
+	results, err := j.FindResults(v)
+	if err != nil {
+		return fmt.Errorf("%q: %w", httpGet.JSONPath, err)
+	}
+	var found []string
+	for _, result := range results {
+		for _, r := range result {
+			found = append(found, fmt.Sprint(r.Interface()))
+		}
+	}
+	if len(found) == 0 {
+		return fmt.Errorf("%q found nothing", httpGet.JSONPath)
+	}
+	if httpGet.JSONPathValue != "" && !slices.Contains(found, httpGet.JSONPathValue) {
+		return fmt.Errorf("%q is %q, not %q", httpGet.JSONPath, strings.Join(found, ","), httpGet.JSONPathValue)
+	}
+	return nil
+}
+
+// grpcProbe checks the service is serving using the gRPC health checking protocol
+func grpcProbe(ctx context.Context, action types.GRPCAction, timeout time.Duration) error {
+	creds := insecure.NewCredentials()
+	if action.TLS {
+		creds = credentials.NewTLS(&tls.Config{InsecureSkipVerify: action.InsecureSkipVerify})
+	}
+	conn, err := grpc.NewClient(action.GetAddress(), grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return fmt.Errorf("failed to connect to %q: %w", action.GetAddress(), err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: action.Service})
+	if err != nil {
+		return fmt.Errorf("failed to check health of %q: %w", action.GetAddress(), err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("%s", resp.Status)
+	}
+	return nil
+}
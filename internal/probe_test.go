@@ -0,0 +1,175 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/kitproj/kit/internal/proc"
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func Test_runProbe(t *testing.T) {
+	ctx := context.Background()
+	task := types.Task{Command: types.Strings{"sleep", "30"}, Env: types.EnvVars{"FOO": "bar"}}
+	p := proc.New("task", task, log.New(os.Stdout, "", 0), types.Spec{})
+
+	t.Run("Exec succeeded", func(t *testing.T) {
+		probe := types.Probe{Exec: &types.ExecAction{Command: types.Strings{"sh", "-c", `test "$FOO" = bar`}}}
+		assert.NoError(t, runProbe(ctx, p, probe))
+	})
+	t.Run("Exec failed", func(t *testing.T) {
+		probe := types.Probe{Exec: &types.ExecAction{Command: types.Strings{"sh", "-c", "echo not ready; exit 1"}}}
+		assert.EqualError(t, runProbe(ctx, p, probe), `"sh -c echo not ready; exit 1" failed: exit status 1: not ready`)
+	})
+	t.Run("Exec timed out", func(t *testing.T) {
+		probe := types.Probe{Exec: &types.ExecAction{Command: types.Strings{"sleep", "3"}}, TimeoutSeconds: 1}
+		assert.EqualError(t, runProbe(ctx, p, probe), `"sleep 3" timed out after 1s`)
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"status":"UP","checks":[{"name":"db","status":"UP"}]}`))
+		case "/hang":
+			<-r.Context().Done()
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	port := types.HTTPGetAction{Host: u.Hostname(), Port: parsePort(u.Port())}
+	withHealth := func(a types.HTTPGetAction) types.Probe {
+		a.Host, a.Port, a.Path = port.Host, port.Port, "/health"
+		a.HTTPHeaders = []types.HTTPHeader{{Name: "Authorization", Value: "Bearer secret"}}
+		return types.Probe{HTTPGet: &a}
+	}
+	t.Run("HTTP succeeded", func(t *testing.T) {
+		assert.NoError(t, runProbe(ctx, p, withHealth(types.HTTPGetAction{})))
+	})
+	t.Run("HTTP status", func(t *testing.T) {
+		probe := types.Probe{HTTPGet: &types.HTTPGetAction{Host: port.Host, Port: port.Port, Path: "/missing"}}
+		assert.EqualError(t, runProbe(ctx, p, probe), `404 Not Found: ""`)
+		probe.HTTPGet.Status = types.StatusCodes{"200-399", "404"}
+		assert.NoError(t, runProbe(ctx, p, probe))
+		probe.HTTPGet.Status = types.StatusCodes{"4xx"}
+		assert.NoError(t, runProbe(ctx, p, probe))
+	})
+	t.Run("HTTP body", func(t *testing.T) {
+		assert.NoError(t, runProbe(ctx, p, withHealth(types.HTTPGetAction{Body: `"UP"`})))
+		assert.EqualError(t, runProbe(ctx, p, withHealth(types.HTTPGetAction{Body: "DOWN"})), `body does not contain "DOWN": "{\"status\":\"UP\",\"checks\":[{\"name\":\"db\",\"status\":\"UP\"}]}"`)
+	})
+	t.Run("HTTP JSONPath", func(t *testing.T) {
+		assert.NoError(t, runProbe(ctx, p, withHealth(types.HTTPGetAction{JSONPath: "{.status}", JSONPathValue: "UP"})))
+		assert.NoError(t, runProbe(ctx, p, withHealth(types.HTTPGetAction{JSONPath: `{.checks[?(@.name=="db")].status}`, JSONPathValue: "UP"})))
+		assert.EqualError(t, runProbe(ctx, p, withHealth(types.HTTPGetAction{JSONPath: "{.status}", JSONPathValue: "DOWN"})), `"{.status}" is "UP", not "DOWN"`)
+		assert.EqualError(t, runProbe(ctx, p, withHealth(types.HTTPGetAction{JSONPath: "{.version}"})), `"{.version}": version is not found`)
+	})
+	t.Run("HTTP timed out", func(t *testing.T) {
+		probe := types.Probe{HTTPGet: &types.HTTPGetAction{Host: port.Host, Port: port.Port, Path: "/hang"}, TimeoutSeconds: 1}
+		start := time.Now()
+		assert.ErrorContains(t, runProbe(ctx, p, probe), "Client.Timeout exceeded")
+		assert.Less(t, time.Since(start), 2*time.Second)
+	})
+	t.Run("HTTPS", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+		u, _ := url.Parse(server.URL)
+		probe := types.Probe{HTTPGet: &types.HTTPGetAction{Scheme: "https", Host: u.Hostname(), Port: parsePort(u.Port())}}
+		assert.ErrorContains(t, runProbe(ctx, p, probe), "certificate signed by unknown authority")
+		probe.HTTPGet.InsecureSkipVerify = true
+		assert.NoError(t, runProbe(ctx, p, probe))
+	})
+	t.Run("gRPC", func(t *testing.T) {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		server := grpc.NewServer()
+		healthServer := health.NewServer()
+		healthpb.RegisterHealthServer(server, healthServer)
+		go func() { _ = server.Serve(lis) }()
+		defer server.Stop()
+		healthServer.SetServingStatus("my.Service", healthpb.HealthCheckResponse_SERVING)
+		healthServer.SetServingStatus("other.Service", healthpb.HealthCheckResponse_NOT_SERVING)
+
+		probe := types.Probe{}
+		assert.NoError(t, probe.Unstring(fmt.Sprintf("grpc://127.0.0.1:%v/my.Service", lis.Addr().(*net.TCPAddr).Port)))
+		assert.NoError(t, runProbe(ctx, p, probe))
+		probe.GRPC.Service = ""
+		assert.NoError(t, runProbe(ctx, p, probe))
+		probe.GRPC.Service = "other.Service"
+		assert.EqualError(t, runProbe(ctx, p, probe), "NOT_SERVING")
+		probe.GRPC.Service = "unknown.Service"
+		assert.ErrorContains(t, runProbe(ctx, p, probe), "code = NotFound")
+	})
+	t.Run("gRPC not listening", func(t *testing.T) {
+		probe := types.Probe{GRPC: &types.GRPCAction{Host: "127.0.0.1", Port: 1}}
+		assert.ErrorContains(t, runProbe(ctx, p, probe), "code = Unavailable")
+	})
+	t.Run("Not supported", func(t *testing.T) {
+		assert.EqualError(t, runProbe(ctx, p, types.Probe{}), "probe not supported")
+	})
+}
+
+func Test_logProbe(t *testing.T) {
+	type result struct {
+		ok      bool
+		message string
+	}
+	run := func(probe types.Probe, output string) []result {
+		var results []result
+		w := logProbe(probe, func(ok bool, message string) {
+			results = append(results, result{ok, message})
+		})
+		_, err := w.Write([]byte(output))
+		assert.NoError(t, err)
+		return results
+	}
+	t.Run("Success", func(t *testing.T) {
+		probe := types.Probe{Log: &types.LogAction{Success: "ready to accept connections"}}
+		results := run(probe, "starting\ndatabase system is ready to accept connections\r\nready to accept connections\n")
+		assert.Equal(t, []result{{true, "database system is ready to accept connections"}}, results)
+	})
+	t.Run("Success threshold", func(t *testing.T) {
+		probe := types.Probe{Log: &types.LogAction{Success: "ready"}, SuccessThreshold: 2}
+		results := run(probe, "ready\nready\npartial rea")
+		assert.Equal(t, []result{{true, "ready"}}, results)
+	})
+	t.Run("Failure", func(t *testing.T) {
+		probe := types.Probe{Log: &types.LogAction{Success: "ready", Failure: "FATAL"}}
+		results := run(probe, "FATAL: no space left on device\n")
+		assert.Equal(t, []result{{false, "FATAL: no space left on device"}}, results)
+	})
+	t.Run("Failure threshold", func(t *testing.T) {
+		probe := types.Probe{Log: &types.LogAction{Failure: "error"}, FailureThreshold: 2}
+		assert.Empty(t, run(probe, "error\nok\n"))
+		assert.Equal(t, []result{{false, "error"}}, run(probe, "error\nerror\n"))
+		assert.Empty(t, run(probe, "error\nnoise\nerror\n"))
+		assert.Equal(t, []result{{false, "error"}}, run(probe, "error\nnoise\nerror\nerror\n"))
+	})
+	t.Run("Invalid pattern", func(t *testing.T) {
+		probe := types.Probe{Log: &types.LogAction{Success: "("}}
+		results := run(probe, "")
+		assert.Equal(t, []result{{false, "invalid pattern: error parsing regexp: missing closing ): `(`"}}, results)
+	})
+}
+
+func parsePort(s string) uint16 {
+	port, _ := strconv.ParseUint(s, 10, 16)
+	return uint16(port)
+}
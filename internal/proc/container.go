@@ -11,6 +11,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
 
 	"github.com/docker/cli/cli/config"
@@ -178,10 +179,16 @@ func (c *container) Run(ctx context.Context, stdout, stderr io.Writer) error {
 	if err = cli.ContainerStart(ctx, id, dockertypes.ContainerStartOptions{}); err != nil {
 		return fmt.Errorf("failed to start container: %w", err)
 	}
+	// closed once the container has exited, so we only stop a container that is still running
+	done := make(chan struct{})
+	defer close(done)
 	go func() {
-		<-ctx.Done()
-		if err := c.stop(context.Background()); err != nil {
-			log.Printf("failed to stop: %v", err)
+		select {
+		case <-ctx.Done():
+			if err := c.stop(context.Background()); err != nil {
+				log.Printf("failed to stop: %v", err)
+			}
+		case <-done:
 		}
 	}()
 	logs, err := cli.ContainerLogs(ctx, c.name, dockertypes.ContainerLogsOptions{
@@ -202,7 +209,7 @@ func (c *container) Run(ctx context.Context, stdout, stderr io.Writer) error {
 	select {
 	case wait := <-waitC:
 		if wait.StatusCode != 0 {
-			return fmt.Errorf("exit code %d", wait.StatusCode)
+			return &ExitError{Code: int(wait.StatusCode)}
 		}
 		return nil
 	case err := <-errC:
@@ -210,6 +217,40 @@ func (c *container) Run(ctx context.Context, stdout, stderr io.Writer) error {
 	}
 }
 
+func (c *container) Exec(ctx context.Context, command []string) error {
+	cli, err := client.NewClientWithOpts(client.FromEnv)
+	if err != nil {
+		return fmt.Errorf("failed to create docker client: %w", err)
+	}
+	defer cli.Close()
+	exec, err := cli.ContainerExecCreate(ctx, c.name, dockertypes.ExecConfig{
+		User:         c.User,
+		AttachStdout: true,
+		AttachStderr: true,
+		Cmd:          command,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create exec: %w", err)
+	}
+	resp, err := cli.ContainerExecAttach(ctx, exec.ID, dockertypes.ExecStartCheck{})
+	if err != nil {
+		return fmt.Errorf("failed to attach to exec: %w", err)
+	}
+	defer resp.Close()
+	output := &bytes.Buffer{}
+	if _, err := stdcopy.StdCopy(output, output, resp.Reader); err != nil {
+		return fmt.Errorf("failed to read exec output: %w", err)
+	}
+	inspect, err := cli.ContainerExecInspect(ctx, exec.ID)
+	if err != nil {
+		return fmt.Errorf("failed to inspect exec: %w", err)
+	}
+	if inspect.ExitCode != 0 {
+		return execError(&ExitError{Code: inspect.ExitCode}, output.Bytes())
+	}
+	return nil
+}
+
 func (c *container) createPorts() (nat.PortSet, map[nat.Port][]nat.PortBinding, error) {
 	portSet := nat.PortSet{}
 	portBindings := map[nat.Port][]nat.PortBinding{}
@@ -260,12 +301,15 @@ func (c *container) stop(ctx context.Context) error {
 	if id == "" {
 		return nil
 	}
+	grace := preStop(c, c.Task, log, c.GetTerminationGracePeriod(c.spec))
 	log.Printf("stopping container\n")
-	grace := c.spec.GetTerminationGracePeriod()
 	timeout := int(grace.Seconds())
-	err = cli.ContainerStop(ctx, id, dockercontainer.StopOptions{
-		Timeout: &timeout,
-	})
+	options := dockercontainer.StopOptions{Timeout: &timeout}
+	// if the task does not have a stop signal, docker uses the image's stop signal
+	if c.StopSignal != "" {
+		options.Signal = strconv.Itoa(int(stopSignal(c.Task, log)))
+	}
+	err = cli.ContainerStop(ctx, id, options)
 	if ignoreNotExist(err) != nil {
 		return fmt.Errorf("failed to stop container: %w", err)
 	}
@@ -38,6 +38,8 @@ func (h *host) Run(ctx context.Context, stdout, stderr io.Writer) error {
 	cmd.SysProcAttr = &syscall.SysProcAttr{
 		Setpgid: true,
 	}
+	// do not kill the process when the context is cancelled, it is stopped gracefully below
+	cmd.Cancel = func() error { return nil }
 	cmd.Env = append(environ, os.Environ()...)
 	log := h.log
 	log.Println("starting process")
@@ -52,26 +54,61 @@ func (h *host) Run(ctx context.Context, stdout, stderr io.Writer) error {
 	if err != nil {
 		return fmt.Errorf("failed get pgid: %w", err)
 	}
+	// closed once the process has exited, so we only stop a process that is still running
+	done := make(chan struct{})
 	go func() {
 		<-ctx.Done()
-		if err := h.stop(pgid); err != nil {
+		if err := h.stop(pgid, done); err != nil {
 			log.Printf("failed to stop process: %v", err)
 		}
 	}()
-	return cmd.Wait()
+	err = cmd.Wait()
+	close(done)
+	return exitError(err)
 }
 
-func (h *host) stop(pid int) error {
+func (h *host) Exec(ctx context.Context, command []string) error {
+	return execHost(ctx, h.spec, h.Task, command)
+}
+
+// execHost runs the command on the host, in the task's working directory and environment
+func execHost(ctx context.Context, spec types.Spec, t types.Task, command []string) error {
+	if len(command) == 0 {
+		return fmt.Errorf("no command")
+	}
+	environ, err := types.Environ(spec, t)
+	if err != nil {
+		return fmt.Errorf("error getting spec environ: %w", err)
+	}
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Dir = t.WorkingDir
+	cmd.Env = append(environ, os.Environ()...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return execError(exitError(err), output)
+	}
+	return nil
+}
+
+func (h *host) stop(pid int, done <-chan struct{}) error {
 	target, err := os.FindProcess(-pid)
 	if err != nil {
 		return fmt.Errorf("failed to find process: %w", err)
 	}
 	log := h.log
-	if err := target.Signal(syscall.SIGTERM); ignoreProcessFinishedErr(err) != nil {
-		log.Printf("failed to terminate: %v", err)
+	select {
+	case <-done:
+		// the process has exited, but it may have left children behind
+	default:
+		gracePeriod := preStop(h, h.Task, log, h.GetTerminationGracePeriod(h.spec))
+		if err := target.Signal(stopSignal(h.Task, log)); ignoreProcessFinishedErr(err) != nil {
+			log.Printf("failed to terminate: %v", err)
+		}
+		select {
+		case <-done:
+		case <-time.After(gracePeriod):
+		}
 	}
-	gracePeriod := h.spec.GetTerminationGracePeriod()
-	time.Sleep(gracePeriod)
 	err = target.Signal(os.Kill)
 	if ignoreProcessFinishedErr(err) != nil {
 		return fmt.Errorf("failed to kill: %w", err)
@@ -27,9 +27,12 @@ import (
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/tools/remotecommand"
 	"k8s.io/client-go/transport/spdy"
 	"k8s.io/utils/strings/slices"
 	"sigs.k8s.io/yaml"
@@ -75,28 +78,9 @@ func (k *k8s) Run(ctx context.Context, stdout io.Writer, stderr io.Writer) error
 		}
 	}
 
-	// connect to the k8s cluster
-	kubeConfig := os.Getenv("KUBECONFIG")
-	if kubeConfig == "" {
-		kubeConfig = clientcmd.RecommendedHomeFile
-	}
-
-	config, err := clientcmd.BuildConfigFromFlags("", kubeConfig)
-	if err != nil {
-		return fmt.Errorf("failed to build config: %w", err)
-	}
-
-	// Get the namespace associated with the current context
-	defaultNamespace, _, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
-		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeConfig},
-		&clientcmd.ConfigOverrides{},
-	).Namespace()
+	config, defaultNamespace, err := k.connect()
 	if err != nil {
-		return fmt.Errorf("failed to get namespace: %w", err)
-	}
-
-	if k.Namespace != "" {
-		defaultNamespace = k.Namespace
+		return err
 	}
 
 	// Create a Kubernetes clientset
@@ -385,6 +369,80 @@ func (k *k8s) Run(ctx context.Context, stdout io.Writer, stderr io.Writer) error
 
 }
 
+// connect returns the config to connect to the cluster, and the namespace to create resources in
+func (k *k8s) connect() (*rest.Config, string, error) {
+	// connect to the k8s cluster
+	kubeConfig := os.Getenv("KUBECONFIG")
+	if kubeConfig == "" {
+		kubeConfig = clientcmd.RecommendedHomeFile
+	}
+
+	config, err := clientcmd.BuildConfigFromFlags("", kubeConfig)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to build config: %w", err)
+	}
+
+	// Get the namespace associated with the current context
+	namespace, _, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeConfig},
+		&clientcmd.ConfigOverrides{},
+	).Namespace()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get namespace: %w", err)
+	}
+
+	if k.Namespace != "" {
+		namespace = k.Namespace
+	}
+	return config, namespace, nil
+}
+
+// Exec runs the command in the first container of the first running pod created by the task.
+func (k *k8s) Exec(ctx context.Context, command []string) error {
+	config, namespace, err := k.connect()
+	if err != nil {
+		return err
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create clientset: %w", err)
+	}
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", nameLabel, k.name)})
+	if err != nil {
+		return fmt.Errorf("failed to list pods: %w", err)
+	}
+	var pod *corev1.Pod
+	for i, p := range pods.Items {
+		if p.Status.Phase == corev1.PodRunning && len(p.Spec.Containers) > 0 {
+			pod = &pods.Items[i]
+			break
+		}
+	}
+	if pod == nil {
+		return fmt.Errorf("no running pod")
+	}
+	req := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(pod.Namespace).
+		Name(pod.Name).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: pod.Spec.Containers[0].Name,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+	executor, err := remotecommand.NewSPDYExecutor(config, "POST", req.URL())
+	if err != nil {
+		return fmt.Errorf("failed to create executor: %w", err)
+	}
+	output := &bytes.Buffer{}
+	if err := executor.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: output, Stderr: output}); err != nil {
+		return execError(err, output.Bytes())
+	}
+	return nil
+}
+
 func sortUnstructureds(uns []*unstructured.Unstructured) {
 	// we need to sort the unstructured outputs by their kind, so that namespaces get applied before deployments, etc
 	// much like Helm/Argo CD does
@@ -3,10 +3,19 @@ package proc
 import (
 	"context"
 	"io"
+
+	"github.com/kitproj/kit/internal/types"
 )
 
-type noop struct{}
+type noop struct {
+	spec types.Spec
+	types.Task
+}
 
 func (n noop) Run(ctx context.Context, stdout, stderr io.Writer) error {
 	return nil
 }
+
+func (n noop) Exec(ctx context.Context, command []string) error {
+	return execHost(ctx, n.spec, n.Task, command)
+}
@@ -2,8 +2,14 @@ package proc
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"log"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/kitproj/kit/internal/types"
 )
@@ -11,6 +17,8 @@ import (
 type Interface interface {
 	// Run runs the process.
 	Run(ctx context.Context, stdout, stderr io.Writer) error
+	// Exec runs a command alongside the process, e.g. in its container, and returns an error if it does not exit with zero.
+	Exec(ctx context.Context, command []string) error
 }
 
 func New(name string, t types.Task, log *log.Logger, spec types.Spec) Interface {
@@ -37,5 +45,60 @@ func New(name string, t types.Task, log *log.Logger, spec types.Spec) Interface
 			spec: spec,
 		}
 	}
-	return &noop{}
+	return &noop{spec: spec, Task: t}
+}
+
+// preStop runs the task's pre-stop command, if it has one, alongside the process. The command is stopped if it does not
+// complete within the grace period, and it returns what is left of the grace period for the process to stop.
+func preStop(p Interface, t types.Task, log *log.Logger, gracePeriod time.Duration) time.Duration {
+	if t.PreStop == nil {
+		return gracePeriod
+	}
+	deadline := time.Now().Add(gracePeriod)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	log.Println("running pre-stop command")
+	if err := p.Exec(ctx, t.PreStop.Command); err != nil {
+		log.Printf("pre-stop command failed: %v", err)
+	}
+	return max(time.Until(deadline), 0)
+}
+
+// stopSignal returns the signal to stop the task with, falling back to SIGTERM if it is not valid, so the task is
+// always stopped
+func stopSignal(t types.Task, log *log.Logger) syscall.Signal {
+	signal, err := t.GetStopSignal()
+	if err != nil {
+		log.Printf("%v, using SIGTERM", err)
+		return syscall.SIGTERM
+	}
+	return signal
+}
+
+// execError returns an error that includes the output, if any, as that usually explains why the command failed
+func execError(err error, output []byte) error {
+	if s := strings.TrimSpace(string(output)); s != "" {
+		return fmt.Errorf("%w: %s", err, s)
+	}
+	return err
+}
+
+// ExitError is returned when the process exits with a non-zero exit code.
+type ExitError struct {
+	// The exit code of the process.
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// exitError converts the error returned by a command that exited with an exit code to an ExitError, so that callers
+// do not need to know how the process was run
+func exitError(err error) error {
+	var x *exec.ExitError
+	if errors.As(err, &x) && x.ExitCode() >= 0 {
+		return &ExitError{Code: x.ExitCode()}
+	}
+	return err
 }
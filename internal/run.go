@@ -7,10 +7,14 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -18,12 +22,29 @@ import (
 	"github.com/kitproj/kit/internal/types"
 	"github.com/kitproj/kit/internal/util"
 	"github.com/pkg/browser"
-	"k8s.io/utils/strings/slices"
 )
 
 var poisonPill = struct{}{}
 
-func RunSubgraph(ctx context.Context, cancel context.CancelFunc, port int, openBrowser bool, logger *log.Logger, wf *types.Workflow, taskNames []string, tasksToSkip []string) error {
+// errStalled is the cause of a task being stopped because it stalled
+var errStalled = errors.New("stalled")
+
+// errTimedOut is the cause of a task being stopped because it ran for longer than its timeout
+var errTimedOut = errors.New("timed out")
+
+// RunOptions are the options for running the tasks of a workflow.
+type RunOptions struct {
+	// The port to serve the UI on, zero disables the UI.
+	Port int
+	// Open the UI in the browser.
+	OpenBrowser bool
+	// The names of the tasks to skip.
+	TasksToSkip []string
+	// Run tasks even if they are up to date.
+	Force bool
+}
+
+func RunSubgraph(ctx context.Context, cancel context.CancelFunc, logger *log.Logger, wf *types.Workflow, taskNames []string, opts RunOptions) error {
 
 	// check that the task names are valid
 	for _, name := range taskNames {
@@ -33,23 +54,27 @@ func RunSubgraph(ctx context.Context, cancel context.CancelFunc, port int, openB
 	}
 
 	// check skipped tasks are valid
-	for _, name := range tasksToSkip {
+	for _, name := range opts.TasksToSkip {
 		if _, ok := wf.Tasks[name]; !ok {
 			return fmt.Errorf("skipped task %q not found in workflow", name)
 		}
 	}
 
-	// name is last part of pwd
-	pwd := os.Getenv("PWD")
-	name := filepath.Base(pwd)
+	name := types.ProjectName()
 
 	dag := NewDAG[bool](name)
 	for name, t := range wf.Tasks {
 		dag.AddNode(name, true)
 		for _, dependency := range t.Dependencies {
-			dag.AddEdge(dependency, name)
+			dag.AddEdge(dependency.Task, name)
 		}
 	}
+
+	// check the graph before we start anything, otherwise tasks would wait forever on dependencies that cannot complete
+	if err := dag.Validate(); err != nil {
+		return fmt.Errorf("invalid workflow: %w", err)
+	}
+
 	visited := dag.Subgraph(taskNames)
 
 	taskByName := wf.Tasks
@@ -57,18 +82,28 @@ func RunSubgraph(ctx context.Context, cancel context.CancelFunc, port int, openB
 	for name := range visited {
 		task := taskByName[name]
 
-		logFile := filepath.Join("logs", fmt.Sprintf("%s.log", name))
+		// task names may contain slashes, e.g. the cell "build[platform=linux/amd64]", so we escape them
+		logFile := filepath.Join("logs", url.PathEscape(name)+".log")
 		if task.Log != "" {
 			logFile = task.Log
 		}
 
+		conditions := map[string]string{}
+		for _, dependency := range task.Dependencies {
+			if dependency.Condition != "" {
+				conditions[dependency.Task] = dependency.Condition
+			}
+		}
+
 		subgraph.AddNode(name, &TaskNode{
-			Name:    name,
-			logFile: logFile,
-			task:    task,
-			Phase:   "pending",
-			cancel:  func() {},
-			mu:      &sync.Mutex{}})
+			Name:       name,
+			logFile:    logFile,
+			task:       task,
+			Phase:      "pending",
+			Conditions: conditions,
+			cancelFunc: func() {},
+			cancelMu:   &sync.Mutex{},
+			mu:         &sync.Mutex{}})
 		for _, parent := range dag.Parents[name] {
 			subgraph.AddEdge(parent, name)
 		}
@@ -76,6 +111,9 @@ func RunSubgraph(ctx context.Context, cancel context.CancelFunc, port int, openB
 
 	events := make(chan any, len(subgraph.Nodes)*2)
 
+	// each task has its own context, so we keep the workflow's to know if we're exiting
+	workflowCtx := ctx
+
 	// schedule the tasks in the subgraph that are ready to run , this is done by sending the task name to the events channel of any task that does not have any parents
 	for taskName := range subgraph.Nodes {
 		if len(subgraph.Parents[taskName]) == 0 {
@@ -97,29 +135,70 @@ func RunSubgraph(ctx context.Context, cancel context.CancelFunc, port int, openB
 	for _, node := range subgraph.Nodes {
 
 		// start watching files for changes
-		watcher, err := fsnotify.NewWatcher()
+		matcher, err := node.task.Matcher()
 		if err != nil {
-			return fmt.Errorf("failed to create watcher: %w", err)
+			return fmt.Errorf("failed to watch %q: %w", node.Name, err)
 		}
-		for _, source := range node.task.Watch {
-			if err := watcher.Add(filepath.Join(node.task.WorkingDir, source)); err != nil {
-				return fmt.Errorf("failed to watch %q: %w", source, err)
+		// the watcher is nil if we are polling
+		var watcher *fsnotify.Watcher
+		var fileEvents <-chan fsnotify.Event
+		if node.task.GetWatchMode(types.Spec(*wf)) == "poll" {
+			p := newPoller(matcher, node.task.GetPollInterval(types.Spec(*wf)))
+			go p.run(ctx)
+			fileEvents = p.Events
+		} else {
+			watcher, err = fsnotify.NewWatcher()
+			if err != nil {
+				return fmt.Errorf("failed to create watcher: %w", err)
 			}
+			dirs, err := matcher.Dirs()
+			if err != nil {
+				return fmt.Errorf("failed to watch %q: %w", node.Name, err)
+			}
+			for _, dir := range dirs {
+				if err := watcher.Add(dir); err != nil {
+					return fmt.Errorf("failed to watch %q: %w", dir, err)
+				}
+			}
+			for _, dir := range dirs {
+				if fstype := util.FilesystemWithoutEvents(dir); fstype != "" {
+					logger.Printf("[%s] warning: %s is on a %s filesystem, which may not deliver file events, consider using `watchMode: poll`\n", node.Name, dir, fstype)
+					break
+				}
+			}
+			defer watcher.Close()
+			fileEvents = watcher.Events
 		}
-		defer watcher.Close()
 
 		go func() {
 			debounceTimer := time.AfterFunc(0, func() {})
 			defer debounceTimer.Stop()
+			// if a watched path is removed or renamed, e.g. replaced by an editor saving a file, we must watch it again
+			removed := &atomic.Bool{}
 			for {
 				select {
 				case <-ctx.Done():
 					return
-				case event := <-watcher.Events:
-					if event.Op&fsnotify.Write == fsnotify.Write {
+				case event := <-fileEvents:
+					// watch directories created since we started
+					if watcher != nil && event.Has(fsnotify.Create) {
+						if stat, err := os.Stat(event.Name); err == nil && stat.IsDir() {
+							watchDirs(watcher, matcher, logger, node.Name, event.Name)
+						}
+					}
+					if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+						removed.Store(true)
+					}
+					if event.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Remove|fsnotify.Rename) != 0 && matcher.Match(event.Name) {
 						debounceTimer.Stop()
-						debounceTimer = time.AfterFunc(100*time.Millisecond, func() {
+						debounceTimer = time.AfterFunc(node.task.GetDebounce(), func() {
+							if removed.Swap(false) && watcher != nil {
+								watchDirs(watcher, matcher, logger, node.Name)
+							}
 							logger.Printf("[%s] %s changed, re-running\n", node.Name, event.Name)
+							node.Trigger = event.Name
+							// the change may fix the task, so it is no longer crash looping
+							node.Restarts = 0
 							events <- node.Name
 						})
 					}
@@ -134,10 +213,10 @@ func RunSubgraph(ctx context.Context, cancel context.CancelFunc, port int, openB
 
 	statusEvents := make(chan *TaskNode, 100)
 
-	if port > 0 {
-		go StartServer(ctx, port, wg, subgraph, statusEvents)
-		if openBrowser {
-			if err := browser.OpenURL(fmt.Sprintf("http://localhost:%d", port)); err != nil {
+	if opts.Port > 0 {
+		go StartServer(ctx, opts.Port, wg, subgraph, statusEvents)
+		if opts.OpenBrowser {
+			if err := browser.OpenURL(fmt.Sprintf("http://localhost:%d", opts.Port)); err != nil {
 				return fmt.Errorf("failed to open browser: %v", err)
 			}
 		}
@@ -147,13 +226,20 @@ func RunSubgraph(ctx context.Context, cancel context.CancelFunc, port int, openB
 	for name, taskNode := range subgraph.Nodes {
 		stalledTime := taskNode.task.GetStalledTimeout()
 		stallTimers[name] = time.AfterFunc(stalledTime, func() {
-			if taskNode.Phase == "starting" || taskNode.Phase == "running" {
-				// we suffix the message with "starting" so we can differentiate between a task that is starting and one that is running, later on we can change the message to "output received"
-				// and restore the phase to "running" or "starting"
+			if taskNode.Phase == "initializing" || taskNode.Phase == "starting" || taskNode.Phase == "running" {
+				// we suffix the message with the phase so we can differentiate between a task that is initializing, starting or running, later on we can change the message to "output received"
+				// and restore the phase
 				taskNode.Message = fmt.Sprintf("no output for %s or more while %s", stalledTime, taskNode.Phase)
 				taskNode.Phase = "stalled"
 				logger.Printf("[%s] %s\n", taskNode.Name, taskNode.Message)
 				statusEvents <- taskNode
+				switch taskNode.task.OnStalled {
+				case "restart":
+					logger.Printf("[%s] restarting because it stalled\n", taskNode.Name)
+					events <- taskNode.Name
+				case "fail":
+					taskNode.stop(fmt.Errorf("%w: %s", errStalled, taskNode.Message))
+				}
 			}
 		})
 	}
@@ -162,9 +248,24 @@ func RunSubgraph(ctx context.Context, cancel context.CancelFunc, port int, openB
 		select {
 		case <-ctx.Done():
 
+			// tasks may still queue events while they are stopping, but we will not run them
+			stopDraining := make(chan struct{})
+			go func() {
+				for {
+					select {
+					case <-events:
+					case <-stopDraining:
+						return
+					}
+				}
+			}()
+
+			stopInOrder(subgraph, logger, types.Spec(*wf))
+
 			logger.Println("waiting for all tasks to complete")
 
 			wg.Wait()
+			close(stopDraining)
 
 			// if any task failed, we will return an error
 			var failures []string
@@ -173,7 +274,7 @@ func RunSubgraph(ctx context.Context, cancel context.CancelFunc, port int, openB
 				color := 30
 				faint := 0
 				switch node.Phase {
-				case "failed":
+				case "failed", "crashloop":
 					// red
 					color = 31
 					faint = 1
@@ -216,7 +317,7 @@ func RunSubgraph(ctx context.Context, cancel context.CancelFunc, port int, openB
 						delete(pending, node.Name)
 					}
 				}
-				if anyJobFailed {
+				if anyJobFailed && !handlingFailure(subgraph) {
 					logger.Println("exiting because a job failed")
 					cancel()
 				}
@@ -228,43 +329,54 @@ func RunSubgraph(ctx context.Context, cancel context.CancelFunc, port int, openB
 			case string:
 				taskName := x
 
-				// we will only execute this task, if its parents are "succeeded" or "skipped" or ("running" and the task is a service)
+				// we will only execute this task, if its parents meet the conditions of its dependencies on them, and a
+				// pending task is skipped if a parent finished without meeting its condition, so that we can exit
+				node := subgraph.Nodes[taskName]
 				blocked := false
-				for _, parentName := range subgraph.Parents[taskName] {
-					parent := subgraph.Nodes[parentName]
-					if parent.blocked() {
-						logger.Printf("task %q is blocked by %q (%s): %s\n", taskName, parentName, parent.Phase, parent.Message)
+				skip := ""
+				for _, dependency := range node.task.Dependencies {
+					parent := subgraph.Nodes[dependency.Task]
+					if unmet(subgraph, dependency.Task, dependency.Condition) && node.Phase == "pending" {
+						skip = fmt.Sprintf("dependency %q %s", dependency.Task, parent.Phase)
+					} else if parent.blocked(dependency.Condition) {
+						logger.Printf("task %q is blocked by %q (%s): %s\n", taskName, dependency.Task, parent.Phase, parent.Message)
 						blocked = true
 					}
 				}
 
-				if blocked {
+				if blocked && skip == "" {
 					continue
 				}
 
 				// we might already be pending, waiting, starting or running this task, so we don't want to start it again
-				node := subgraph.Nodes[taskName]
 
 				node.cancel()
 
 				// each task is executed in a separate goroutine
 				wg.Add(1)
 
-				go func(node *TaskNode) {
+				go func(node *TaskNode, skip string) {
 
 					// lock the task, so we do not run two instances of it at the same time
 					node.mu.Lock()
 
-					ctx, cancel := context.WithCancel(ctx)
+					// tasks are not cancelled when the workflow is, they are stopped in order
+					ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
 					defer cancel()
 
-					node.cancel = cancel
+					node.setCancel(cancel)
 
 					// send a poison pill to indicate that we've finish and the main loop must check to see if we need to exit
 					defer func() { events <- poisonPill }()
 					defer wg.Done()
 					defer node.mu.Unlock()
 
+					// we're exiting, so we must not start the task, this is checked after the cancel function is set, so
+					// stopInOrder either cancels this run, or this run sees the workflow is done
+					if workflowCtx.Err() != nil {
+						return
+					}
+
 					t := node.task
 
 					var out io.Writer = funcWriter(func(p []byte) (int, error) {
@@ -290,32 +402,73 @@ func RunSubgraph(ctx context.Context, cancel context.CancelFunc, port int, openB
 					logger := log.New(out, "", 0)
 
 					setNodeStatus := func(node *TaskNode, phase string, message string) {
+						previous := *node
 						node.Phase = phase
 						node.Message = message
 						stallTimers[node.Name].Reset(node.task.GetStalledTimeout())
 						logger.Println(node.Message)
 						statusEvents <- node
-					}
-
-					setNodeStatus(node, "waiting", "")
-
-					queueChildren := func() {
+						// hooks run in their own goroutine, so a slow hook does not hold up the task or its dependents
+						if name, hook := node.task.Hooks.For(previous.Phase, phase); hook != nil {
+							startHook(wg, types.Spec(*wf), *node, name, hook, logger)
+						}
+						// queue the children whose dependency on this task has just been met, and stop those that restart
+						// with their dependencies when it is no longer met, they are queued again once it is met, as
+						// dependencies cannot have cycles, this cannot cause tasks to restart each other forever, pending
+						// children are also queued when this task finishes without meeting it, so that they are skipped
 						for _, child := range subgraph.Children[node.Name] {
 							// only queue tasks in the subgraph
-							if _, ok := subgraph.Nodes[child]; ok {
-								logger.Printf("queuing %q\n", child)
-								events <- child
+							if childNode, ok := subgraph.Nodes[child]; ok {
+								condition := childNode.task.Dependencies.Condition(node.Name)
+								if previous.blocked(condition) && !node.blocked(condition) {
+									logger.Printf("queuing %q\n", child)
+									events <- child
+								} else if !previous.blocked(condition) && node.blocked(condition) && childNode.task.RestartWithDependencies && workflowCtx.Err() == nil {
+									logger.Printf("stopping %q\n", child)
+									childNode.cancel()
+								} else if !previous.unmet(condition) && node.unmet(condition) && childNode.Phase == "pending" {
+									logger.Printf("queuing %q\n", child)
+									events <- child
+								}
 							}
 						}
 					}
 
-					// if the task can be skipped, lets exit early
-					if t.Skip() || slices.Contains(tasksToSkip, node.Name) {
+					setNodeStatus(node, "waiting", "")
+
+					if slices.Contains(opts.TasksToSkip, node.Name) {
 						setNodeStatus(node, "skipped", "")
-						queueChildren()
 						return
 					}
 
+					if skip != "" {
+						setNodeStatus(node, "skipped", skip)
+						return
+					}
+
+					// the checksums of the inputs, recorded once the task succeeds
+					var inputs types.Inputs
+					if t.Checksum {
+						var err error
+						inputs, err = t.Inputs(types.Spec(*wf))
+						if err != nil {
+							setNodeStatus(node, "failed", fmt.Sprintf("failed to checksum inputs: %v", err))
+							return
+						}
+					}
+
+					// if the task can be skipped, lets exit early, unless we're forced to run it
+					if reason, err := t.Outdated(node.Name, inputs); err != nil {
+						logger.Printf("running because the last run is unknown: %v\n", err)
+					} else if reason == "" && opts.Force {
+						logger.Println("running because forced")
+					} else if reason == "" {
+						setNodeStatus(node, "skipped", "up to date")
+						return
+					} else if t.Checksum || len(t.Targets) > 0 {
+						logger.Printf("running because %s\n", reason)
+					}
+
 					// if the task needs a mutex, lets wait for it
 					if t.Mutex != "" {
 						mu := util.GetMutex(t.Mutex)
@@ -337,65 +490,133 @@ func RunSubgraph(ctx context.Context, cancel context.CancelFunc, port int, openB
 						defer sema.Release(1)
 					}
 
+					// the log file is created before the task starts, as hooks append to it as the task changes phase
+					file, err := os.OpenFile(node.logFile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY|os.O_APPEND, 0o666)
+					if err != nil {
+						setNodeStatus(node, "failed", fmt.Sprintf("failed to create log file: %v", err))
+						return
+					}
+					defer file.Close()
+
 					p := proc.New(taskName, t, logger, types.Spec(*wf))
 
-					if probe := t.GetLivenessProbe(); probe != nil {
-						liveFunc := func(live bool, err error) {
-							if !live {
-								setNodeStatus(node, "failed", fmt.Sprintf("liveness probe failed: %v", err))
-								cancel()
-							}
-						}
-						go probeLoop(ctx, *probe, liveFunc)
+					// the process is stopped with the cause if the startup or liveness probe fails
+					runCtx, stopProcess := context.WithCancelCause(ctx)
+					defer stopProcess(nil)
+					node.stop = stopProcess
+
+					// log probes watch the task's output, and are added as the probes start
+					var probeMu sync.Mutex
+					var probeWriters []io.Writer
+					addProbe := func(ctx context.Context, probe types.Probe, callback func(ok bool, message string)) {
+						w := startProbe(ctx, p, probe, callback)
+						probeMu.Lock()
+						defer probeMu.Unlock()
+						probeWriters = append(probeWriters, w)
 					}
-					if probe := t.GetReadinessProbe(); probe != nil {
-						readyFunc := func(ready bool, err error) {
-							if ready {
-								setNodeStatus(node, "running", "readiness probe succeeded")
-								queueChildren()
+					probeOut := funcWriter(func(b []byte) (int, error) {
+						probeMu.Lock()
+						writers := append([]io.Writer(nil), probeWriters...)
+						probeMu.Unlock()
+						for _, w := range writers {
+							_, _ = w.Write(b)
+						}
+						return len(b), nil
+					})
+
+					startProbes := func() {
+						if t.GetType() == types.TaskTypeService {
+							if t.GetReadinessProbe() != nil {
+								setNodeStatus(node, "starting", "service starting")
 							} else {
-								setNodeStatus(node, "failed", fmt.Sprintf("readiness probe failed: %v", err))
-								cancel()
+								setNodeStatus(node, "running", "no ports to expose")
+							}
+						} else {
+							// non a service, must be a job
+							setNodeStatus(node, "running", "job running")
+						}
+						if probe := t.GetLivenessProbe(); probe != nil {
+							liveFunc := func(live bool, message string) {
+								if !live {
+									stopProcess(fmt.Errorf("liveness probe failed: %s", message))
+								}
+							}
+							addProbe(ctx, *probe, liveFunc)
+						}
+						if probe := t.GetReadinessProbe(); probe != nil {
+							readyFunc := func(ready bool, message string) {
+								if ready {
+									if message == "" {
+										message = "readiness probe succeeded"
+									}
+									setNodeStatus(node, "running", message)
+								} else {
+									setNodeStatus(node, "failed", fmt.Sprintf("readiness probe failed: %s", message))
+									cancel()
+								}
 							}
+							addProbe(ctx, *probe, readyFunc)
 						}
-						go probeLoop(ctx, *probe, readyFunc)
 					}
 
-					if t.GetType() == types.TaskTypeService {
-						if t.Ports != nil {
-							setNodeStatus(node, "starting", "service starting")
-						} else {
-							setNodeStatus(node, "running", "no ports to expose")
-							queueChildren()
-						}
+					// liveness and readiness probes only start once the startup probe succeeds
+					if probe := t.GetStartupProbe(); probe != nil {
+						setNodeStatus(node, "initializing", "waiting for startup probe")
+						startupCtx, stopStartup := context.WithCancel(ctx)
+						defer stopStartup()
+						var started atomic.Bool
+						addProbe(startupCtx, *probe, func(ok bool, message string) {
+							if started.Load() {
+								return
+							}
+							if ok {
+								started.Store(true)
+								stopStartup()
+								logger.Println("startup probe succeeded")
+								startProbes()
+							} else {
+								stopProcess(fmt.Errorf("startup probe failed: %s", message))
+							}
+						})
 					} else {
-						// non a service, must be a job
-						setNodeStatus(node, "running", "job running")
+						startProbes()
 					}
 
-					restart := func() {
+					// when the task was started, so we know if it ran long enough to reset the restart delay
+					var startedAt time.Time
+
+					restart := func(failed bool) {
+						if !failed || time.Since(startedAt) >= t.GetRestartResetAfter() {
+							node.Restarts = 0
+						}
+						if failed && t.MaxRestarts > 0 && node.Restarts >= t.MaxRestarts {
+							setNodeStatus(node, "failed", fmt.Sprintf("%s, not restarting after %d restarts", node.Message, node.Restarts))
+							return
+						}
+						delay := t.GetRestartDelay(node.Restarts)
+						if failed && node.Restarts > 0 {
+							setNodeStatus(node, "crashloop", fmt.Sprintf("%s, restarting in %v", node.Message, delay))
+						} else {
+							logger.Printf("restarting in %v\n", delay)
+						}
 						select {
 						case <-ctx.Done():
-						case <-time.After(3 * time.Second):
+						case <-time.After(delay):
+							node.Restarts++
 							logger.Println("restarting")
 							cancel()
 							events <- node.Name
 						}
 					}
 
-					file, err := os.Create(node.logFile)
-					if err != nil {
-						setNodeStatus(node, "failed", fmt.Sprintf("failed to create log file: %v", err))
-						return
-					}
-					defer file.Close()
-
 					// if the task has a log file, we will write to that file, we sync after each write
 					// so when we tail the log file, we see the output immediately
 					buf := funcWriter(func(p []byte) (int, error) {
 						stallTimers[node.Name].Reset(node.task.GetStalledTimeout())
 						if node.Phase == "stalled" {
-							if strings.HasSuffix(node.Message, "starting") {
+							if strings.HasSuffix(node.Message, "initializing") {
+								setNodeStatus(node, "initializing", "output received")
+							} else if strings.HasSuffix(node.Message, "starting") {
 								setNodeStatus(node, "starting", "output received")
 							} else {
 								setNodeStatus(node, "running", "output received")
@@ -416,32 +637,229 @@ func RunSubgraph(ctx context.Context, cancel context.CancelFunc, port int, openB
 					} else {
 						out = io.MultiWriter(out, buf)
 					}
+					out = io.MultiWriter(out, probeOut)
+
+					// lines of output that match failOn fail the task, and those that match warnOn are shown in its message
+					failOn, err := compilePatterns(t.FailOn)
+					if err != nil {
+						setNodeStatus(node, "failed", fmt.Sprintf("invalid failOn pattern: %v", err))
+						return
+					}
+					warnOn, err := compilePatterns(t.WarnOn)
+					if err != nil {
+						setNodeStatus(node, "failed", fmt.Sprintf("invalid warnOn pattern: %v", err))
+						return
+					}
+					var outputErr error
+					var warning string
+					patternsOut := newLineWriter(func(line string) {
+						for _, r := range failOn {
+							if r.MatchString(line) {
+								err := fmt.Errorf("output matched %q: %s", r, line)
+								// services are stopped straight away, jobs once they exit
+								if t.GetType() == types.TaskTypeService {
+									stopProcess(err)
+								} else if outputErr == nil {
+									outputErr = err
+								}
+								return
+							}
+						}
+						for _, r := range warnOn {
+							if r.MatchString(line) {
+								warning = "warning: " + line
+								setNodeStatus(node, node.Phase, warning)
+								return
+							}
+						}
+					})
+					if len(failOn) > 0 || len(warnOn) > 0 {
+						out = io.MultiWriter(out, patternsOut)
+					}
 
-					err = p.Run(ctx, out, out)
+					if timeout := t.Timeout; timeout != nil {
+						timer := time.AfterFunc(timeout.Duration, func() {
+							stopProcess(fmt.Errorf("%w after %v", errTimedOut, timeout.Duration))
+						})
+						defer timer.Stop()
+					}
+
+					startedAt = time.Now()
+					err = p.Run(runCtx, out, out)
 					// if the task was cancelled, we don't want to restart it, this is normal exit
 					if errors.Is(ctx.Err(), context.Canceled) {
 						setNodeStatus(node, "cancelled", "")
 						return
 					}
+					// the process was stopped, e.g. because a probe failed or it timed out
+					if runCtx.Err() != nil {
+						err = context.Cause(runCtx)
+					}
+
+					patternsOut.Flush()
+
+					// some exit codes mean the task succeeded, or that it had nothing to do, unless its output failed it
+					message := ""
+					var exitErr *proc.ExitError
+					if errors.As(err, &exitErr) {
+						if slices.Contains(t.SkipExitCodes, exitErr.Code) {
+							if outputErr == nil {
+								setNodeStatus(node, "skipped", fmt.Sprintf("%v, a skip exit code", err))
+								return
+							}
+							err = nil
+						}
+						if slices.Contains(t.SuccessExitCodes, exitErr.Code) {
+							message = fmt.Sprintf("%v, a success exit code", err)
+							err = nil
+						}
+					}
+
+					if err == nil {
+						err = outputErr
+					}
 
 					if err != nil {
 						setNodeStatus(node, "failed", fmt.Sprint(err))
-						if t.GetRestartPolicy() != "Never" {
-							restart()
+						// a task that is failed because it stalled or timed out is not restarted
+						if t.GetRestartPolicy() != "Never" && !errors.Is(err, errStalled) && !errors.Is(err, errTimedOut) {
+							restart(true)
 						}
 						return
 					}
 
-					setNodeStatus(node, "succeeded", "")
+					if inputs != nil {
+						if err := types.WriteInputs(node.Name, inputs); err != nil {
+							logger.Printf("failed to record checksums: %v\n", err)
+						}
+					}
+
+					if message == "" {
+						message = warning
+					}
+					setNodeStatus(node, "succeeded", message)
 					if t.GetRestartPolicy() == "Always" {
-						restart()
+						restart(false)
 					}
-					queueChildren()
 
-				}(node)
+				}(node, skip)
 			default:
 				panic(fmt.Sprintf("unexpected event: %v", event))
 			}
 		}
 	}
 }
+
+// watchDirs adds the directories that must be watched within the roots to the watcher, or all of them if there are no roots
+func watchDirs(watcher *fsnotify.Watcher, matcher *types.Matcher, logger *log.Logger, name string, roots ...string) {
+	var dirs []string
+	var err error
+	if len(roots) == 0 {
+		dirs, err = matcher.Dirs()
+	} else {
+		dirs, err = matcher.DirsIn(roots...)
+	}
+	if err != nil {
+		logger.Printf("[%s] failed to watch: %v\n", name, err)
+	}
+	for _, dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			logger.Printf("[%s] failed to watch %q: %v\n", name, dir, err)
+		}
+	}
+}
+
+// stopInOrder stops the tasks in reverse dependency order, so that tasks are stopped before the tasks they depend on,
+// e.g. an API before its database. It waits for each tier of tasks to stop, or for their grace period, before stopping
+// the next tier.
+func stopInOrder(subgraph DAG[*TaskNode], logger *log.Logger, spec types.Spec) {
+	for _, tier := range subgraph.ReverseTiers() {
+		var gracePeriod time.Duration
+		stopped := map[string]chan struct{}{}
+		for _, name := range tier {
+			node := subgraph.Nodes[name]
+			// if we can lock the task, it is not running
+			if node.mu.TryLock() {
+				node.mu.Unlock()
+				continue
+			}
+			logger.Printf("[%s] stopping\n", name)
+			gracePeriod = max(gracePeriod, node.task.GetTerminationGracePeriod(spec))
+			node.cancel()
+			done := make(chan struct{})
+			go func() {
+				node.mu.Lock()
+				defer node.mu.Unlock()
+				close(done)
+			}()
+			stopped[name] = done
+		}
+		timeout := time.After(gracePeriod)
+		for _, name := range tier {
+			done, ok := stopped[name]
+			if !ok {
+				continue
+			}
+			select {
+			case <-done:
+				logger.Printf("[%s] stopped\n", name)
+			case <-timeout:
+				logger.Printf("[%s] still stopping after %v, stopping the next tasks\n", name, gracePeriod)
+			}
+		}
+	}
+}
+
+// unmet returns true if a task finished without meeting the condition of a dependency on it, or it was skipped because
+// the conditions of its own dependencies were not met, so that tasks are skipped in turn
+func unmet(subgraph DAG[*TaskNode], name string, condition string) bool {
+	node := subgraph.Nodes[name]
+	if node.unmet(condition) {
+		return true
+	}
+	if node.Phase == "skipped" {
+		for _, dependency := range node.task.Dependencies {
+			if unmet(subgraph, dependency.Task, dependency.Condition) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// handlingFailure returns true if a task that depends on a failed task completing or failing, e.g. to clean up or
+// report, is yet to finish
+func handlingFailure(subgraph DAG[*TaskNode]) bool {
+	for _, node := range subgraph.Nodes {
+		switch node.Phase {
+		case "succeeded", "failed", "skipped", "cancelled":
+			continue
+		}
+		handler, blocked := false, false
+		for _, dependency := range node.task.Dependencies {
+			parent := subgraph.Nodes[dependency.Task]
+			if parent.blocked(dependency.Condition) {
+				blocked = true
+			} else if parent.Phase == "failed" {
+				handler = true
+			}
+		}
+		if handler && !blocked {
+			return true
+		}
+	}
+	return false
+}
+
+// compilePatterns compiles the regular expressions
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		r, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, r)
+	}
+	return compiled, nil
+}
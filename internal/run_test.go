@@ -5,6 +5,7 @@ import (
 	"context"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/kitproj/kit/internal/types"
 	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestRunSubgraph(t *testing.T) {
@@ -31,24 +33,37 @@ func TestRunSubgraph(t *testing.T) {
 	t.Run("No tasks", func(t *testing.T) {
 		ctx, cancel, logger, _ := setup(t)
 		defer cancel()
-		err := RunSubgraph(ctx, cancel, 0, false, logger, &types.Workflow{}, nil, nil)
+		err := RunSubgraph(ctx, cancel, logger, &types.Workflow{}, nil, RunOptions{})
 		assert.NoError(t, err)
 	})
 
 	t.Run("Task not found", func(t *testing.T) {
 		ctx, cancel, logger, _ := setup(t)
 		defer cancel()
-		err := RunSubgraph(ctx, cancel, 0, false, logger, &types.Workflow{}, []string{"job"}, nil)
+		err := RunSubgraph(ctx, cancel, logger, &types.Workflow{}, []string{"job"}, RunOptions{})
 		assert.EqualError(t, err, "task \"job\" not found in workflow")
 	})
 
 	t.Run("Skipped task not found", func(t *testing.T) {
 		ctx, cancel, logger, _ := setup(t)
 		defer cancel()
-		err := RunSubgraph(ctx, cancel, 0, false, logger, &types.Workflow{}, nil, []string{"job"})
+		err := RunSubgraph(ctx, cancel, logger, &types.Workflow{}, nil, RunOptions{TasksToSkip: []string{"job"}})
 		assert.EqualError(t, err, "skipped task \"job\" not found in workflow")
 	})
 
+	t.Run("Dependency cycle", func(t *testing.T) {
+		ctx, cancel, logger, _ := setup(t)
+		defer cancel()
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"a": {Command: []string{"true"}, Dependencies: types.Dependencies{{Task: "b"}}},
+				"b": {Command: []string{"true"}, Dependencies: types.Dependencies{{Task: "a"}}},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, logger, wf, []string{"a"}, RunOptions{})
+		assert.EqualError(t, err, "invalid workflow: dependency cycle: a -> b -> a")
+	})
+
 	t.Run("Single successful job", func(t *testing.T) {
 		ctx, cancel, logger, _ := setup(t)
 		defer cancel()
@@ -57,7 +72,7 @@ func TestRunSubgraph(t *testing.T) {
 				"job": {Command: []string{"true"}},
 			},
 		}
-		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"job"}, nil)
+		err := RunSubgraph(ctx, cancel, logger, wf, []string{"job"}, RunOptions{})
 		assert.NoError(t, err)
 	})
 
@@ -69,7 +84,7 @@ func TestRunSubgraph(t *testing.T) {
 				"job": {Command: []string{"false"}},
 			},
 		}
-		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"job"}, nil)
+		err := RunSubgraph(ctx, cancel, logger, wf, []string{"job"}, RunOptions{})
 		assert.EqualError(t, err, "failed tasks: [job]")
 	})
 
@@ -87,7 +102,7 @@ func TestRunSubgraph(t *testing.T) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"service"}, nil)
+			err := RunSubgraph(ctx, cancel, logger, wf, []string{"service"}, RunOptions{})
 			assert.NoError(t, err)
 		}()
 
@@ -114,7 +129,7 @@ func TestRunSubgraph(t *testing.T) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"service"}, nil)
+			err := RunSubgraph(ctx, cancel, logger, wf, []string{"service"}, RunOptions{})
 			assert.EqualError(t, err, "failed tasks: [service]")
 		}()
 
@@ -135,7 +150,7 @@ func TestRunSubgraph(t *testing.T) {
 				"job": {Command: []string{"echo", "hello"}, Log: "test.log"},
 			},
 		}
-		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"job"}, nil)
+		err := RunSubgraph(ctx, cancel, logger, wf, []string{"job"}, RunOptions{})
 		assert.NoError(t, err)
 		assert.NotContains(t, buffer.String(), "hello")
 		assert.Contains(t, buffer.String(), "[job] (succeeded)")
@@ -160,7 +175,7 @@ func TestRunSubgraph(t *testing.T) {
 		go func() {
 			defer wg.Done()
 
-			err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"job", "job"}, nil)
+			err := RunSubgraph(ctx, cancel, logger, wf, []string{"job", "job"}, RunOptions{})
 			assert.NoError(t, err)
 		}()
 
@@ -191,7 +206,7 @@ sleep 30
 		go func() {
 			defer wg.Done()
 
-			err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"job", "service"}, nil)
+			err := RunSubgraph(ctx, cancel, logger, wf, []string{"job", "service"}, RunOptions{})
 			assert.NoError(t, err)
 		}()
 
@@ -221,6 +236,760 @@ sleep 30
 		assert.Equal(t, 2, count)
 	})
 
+	t.Run("Restart service by modifying a file in a new directory", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		dir := t.TempDir()
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"service": {
+					Command:    []string{"sleep", "30"},
+					WorkingDir: dir,
+					Watch:      []string{"**/*.go", "!**/*_test.go"},
+					Ports:      []types.Port{{}},
+				},
+			},
+		}
+
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := RunSubgraph(ctx, cancel, logger, wf, []string{"service"}, RunOptions{})
+			assert.NoError(t, err)
+		}()
+
+		sleep(t)
+
+		assert.NoError(t, os.Mkdir(filepath.Join(dir, "pkg"), 0755))
+		sleep(t)
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "pkg", "main_test.go"), []byte("package pkg"), 0644))
+		sleep(t)
+		assert.NotContains(t, buffer.String(), "changed, re-running")
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "pkg", "main.go"), []byte("package pkg"), 0644))
+		sleep(t)
+
+		cancel()
+
+		wg.Wait()
+
+		assert.Contains(t, buffer.String(), "[service] "+filepath.Join(dir, "pkg", "main.go")+" changed, re-running")
+	})
+
+	t.Run("Restart service by creating, renaming and removing watched files", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		dir := t.TempDir()
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"service": {
+					Command:    []string{"sleep", "30"},
+					WorkingDir: dir,
+					Watch:      []string{"*.go"},
+					Debounce:   &metav1.Duration{Duration: 50 * time.Millisecond},
+					Ports:      []types.Port{{}},
+				},
+			},
+		}
+
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := RunSubgraph(ctx, cancel, logger, wf, []string{"service"}, RunOptions{})
+			assert.NoError(t, err)
+		}()
+
+		sleep(t)
+
+		a, b := filepath.Join(dir, "a.go"), filepath.Join(dir, "b.go")
+		assert.NoError(t, os.WriteFile(a, []byte("package a"), 0644))
+		sleep(t)
+		// an editor saves by writing a temporary file and renaming it over the original
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "a.go.tmp"), []byte("package b"), 0644))
+		assert.NoError(t, os.Rename(filepath.Join(dir, "a.go.tmp"), a))
+		sleep(t)
+		assert.NoError(t, os.Rename(a, b))
+		sleep(t)
+		assert.NoError(t, os.Remove(b))
+		sleep(t)
+
+		cancel()
+
+		wg.Wait()
+
+		assert.Equal(t, 4, strings.Count(buffer.String(), "changed, re-running"))
+		assert.Contains(t, buffer.String(), "[service] "+b+" changed, re-running")
+	})
+
+	t.Run("Restart service after a watched directory is replaced", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		dir := t.TempDir()
+		src := filepath.Join(dir, "src")
+		assert.NoError(t, os.Mkdir(src, 0755))
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"service": {
+					Command:    []string{"sleep", "30"},
+					WorkingDir: dir,
+					Watch:      []string{"src"},
+					Ports:      []types.Port{{}},
+				},
+			},
+		}
+
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := RunSubgraph(ctx, cancel, logger, wf, []string{"service"}, RunOptions{})
+			assert.NoError(t, err)
+		}()
+
+		sleep(t)
+
+		assert.NoError(t, os.Remove(src))
+		assert.NoError(t, os.Mkdir(src, 0755))
+		sleep(t)
+		assert.NoError(t, os.WriteFile(filepath.Join(src, "main.go"), []byte("package main"), 0644))
+		sleep(t)
+
+		cancel()
+
+		wg.Wait()
+
+		assert.Contains(t, buffer.String(), "[service] "+filepath.Join(src, "main.go")+" changed, re-running")
+	})
+
+	t.Run("Restart service by polling", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		dir := t.TempDir()
+		wf := &types.Workflow{
+			WatchMode:    "poll",
+			PollInterval: &metav1.Duration{Duration: 20 * time.Millisecond},
+			Tasks: map[string]types.Task{
+				"service": {
+					Command:    []string{"sleep", "30"},
+					WorkingDir: dir,
+					Watch:      []string{"."},
+					Ports:      []types.Port{{}},
+				},
+			},
+		}
+
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := RunSubgraph(ctx, cancel, logger, wf, []string{"service"}, RunOptions{})
+			assert.NoError(t, err)
+		}()
+
+		sleep(t)
+
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main"), 0644))
+		sleep(t)
+
+		cancel()
+
+		wg.Wait()
+
+		assert.Contains(t, buffer.String(), "[service] "+filepath.Join(dir, "main.go")+" changed, re-running")
+	})
+
+	t.Run("Service ready when its output matches", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"service": {
+					Sh:             "echo starting; sleep 0.2; echo database system is ready to accept connections; sleep 30",
+					ReadinessProbe: &types.Probe{Log: &types.LogAction{Success: "ready to accept connections"}},
+				},
+				"job": {Command: []string{"true"}, Dependencies: types.Dependencies{{Task: "service"}}},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, logger, wf, []string{"job"}, RunOptions{})
+		assert.NoError(t, err)
+		assert.Contains(t, buffer.String(), "[service] (running)  database system is ready to accept connections")
+	})
+
+	t.Run("Startup probe gates readiness", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"service": {
+					Sh:             "echo ready too soon; sleep 0.2; echo started; sleep 0.2; echo ready; sleep 30",
+					StartupProbe:   &types.Probe{Log: &types.LogAction{Success: "started"}},
+					ReadinessProbe: &types.Probe{Log: &types.LogAction{Success: "ready"}},
+				},
+				"job": {Command: []string{"true"}, Dependencies: types.Dependencies{{Task: "service"}}},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, logger, wf, []string{"job"}, RunOptions{})
+		assert.NoError(t, err)
+		assert.Contains(t, buffer.String(), "[service] (initializing)  waiting for startup probe")
+		assert.Contains(t, buffer.String(), "[service] (initializing)  startup probe succeeded")
+		assert.Contains(t, buffer.String(), "[service] (running)  ready\033[0m")
+		assert.NotContains(t, buffer.String(), "(running)  ready too soon")
+	})
+
+	t.Run("Startup probe failure restarts service", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"service": {
+					Sh:            "echo failed to bind; sleep 30",
+					StartupProbe:  &types.Probe{Log: &types.LogAction{Success: "started", Failure: "failed to bind"}},
+					RestartPolicy: "OnFailure",
+				},
+			},
+		}
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := RunSubgraph(ctx, cancel, logger, wf, []string{"service"}, RunOptions{})
+			assert.EqualError(t, err, "failed tasks: [service]")
+		}()
+
+		time.Sleep(4 * time.Second)
+		cancel()
+		wg.Wait()
+
+		assert.Contains(t, buffer.String(), "[service] (failed)  startup probe failed: failed to bind")
+		assert.Contains(t, buffer.String(), "restarting")
+		assert.Equal(t, 2, strings.Count(buffer.String(), "waiting for startup probe"))
+	})
+
+	t.Run("Crash looping service is failed after max restarts", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"service": {
+					Command:       []string{"false"},
+					Type:          types.TaskTypeService,
+					RestartPolicy: "OnFailure",
+					RestartDelay:  &metav1.Duration{Duration: 100 * time.Millisecond},
+					MaxRestarts:   2,
+				},
+			},
+		}
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := RunSubgraph(ctx, cancel, logger, wf, []string{"service"}, RunOptions{})
+			assert.EqualError(t, err, "failed tasks: [service]")
+		}()
+
+		time.Sleep(time.Second)
+		cancel()
+		wg.Wait()
+
+		assert.Contains(t, buffer.String(), "[service] (failed)  restarting in 100ms")
+		assert.Contains(t, buffer.String(), "[service] (crashloop)  exit status 1, restarting in 200ms")
+		assert.Contains(t, buffer.String(), "[service] (failed)  exit status 1, not restarting after 2 restarts")
+		assert.Equal(t, 3, strings.Count(buffer.String(), "starting process"))
+	})
+
+	t.Run("Job times out", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"job": {Command: []string{"sleep", "30"}, Timeout: &metav1.Duration{Duration: 200 * time.Millisecond}},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, logger, wf, []string{"job"}, RunOptions{})
+		assert.EqualError(t, err, "failed tasks: [job]")
+		assert.Contains(t, buffer.String(), "[job] (failed)  timed out after 200ms")
+	})
+
+	t.Run("Timed out service is not restarted", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"service": {
+					Command:       []string{"sleep", "30"},
+					Type:          types.TaskTypeService,
+					RestartPolicy: "OnFailure",
+					Timeout:       &metav1.Duration{Duration: 200 * time.Millisecond},
+				},
+			},
+		}
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := RunSubgraph(ctx, cancel, logger, wf, []string{"service"}, RunOptions{})
+			assert.EqualError(t, err, "failed tasks: [service]")
+		}()
+
+		time.Sleep(time.Second)
+		cancel()
+		wg.Wait()
+
+		out := buffer.String()
+		assert.Contains(t, out, "[service] (failed)  timed out after 200ms")
+		assert.NotContains(t, out, "restarting")
+		assert.Equal(t, 1, strings.Count(out, "starting process"))
+	})
+
+	t.Run("Stalled job fails", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"job": {
+					Command:        []string{"sleep", "30"},
+					StalledTimeout: &metav1.Duration{Duration: 200 * time.Millisecond},
+					OnStalled:      "fail",
+					RestartPolicy:  "OnFailure",
+				},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, logger, wf, []string{"job"}, RunOptions{})
+		assert.EqualError(t, err, "failed tasks: [job]")
+		assert.Contains(t, buffer.String(), "[job] (failed)  stalled: no output for 200ms or more while running")
+		assert.NotContains(t, buffer.String(), "restarting")
+	})
+
+	t.Run("Stalled service restarts", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"service": {
+					Command:        []string{"sleep", "30"},
+					Type:           types.TaskTypeService,
+					StalledTimeout: &metav1.Duration{Duration: 200 * time.Millisecond},
+					OnStalled:      "restart",
+				},
+			},
+		}
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := RunSubgraph(ctx, cancel, logger, wf, []string{"service"}, RunOptions{})
+			assert.NoError(t, err)
+		}()
+
+		time.Sleep(time.Second)
+		cancel()
+		wg.Wait()
+
+		assert.Contains(t, buffer.String(), "[service] restarting because it stalled")
+		assert.GreaterOrEqual(t, strings.Count(buffer.String(), "starting process"), 2)
+	})
+
+	t.Run("Success and skip exit codes", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"lint":     {Sh: "exit 3", SuccessExitCodes: []int{3}},
+				"generate": {Sh: "exit 2", SkipExitCodes: []int{2}},
+				"build":    {Sh: "exit 2", SuccessExitCodes: []int{3}, Dependencies: types.Dependencies{{Task: "lint"}, {Task: "generate"}}},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, logger, wf, []string{"build"}, RunOptions{})
+		assert.EqualError(t, err, "failed tasks: [build]")
+		assert.Contains(t, buffer.String(), "[lint] (succeeded)  exit status 3, a success exit code")
+		assert.Contains(t, buffer.String(), "[generate] (skipped)  exit status 2, a skip exit code")
+		assert.Contains(t, buffer.String(), "[build] (failed)  exit status 2")
+	})
+
+	t.Run("Job output matches a pattern", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"warn": {Sh: "echo WARN: deprecated; echo done", WarnOn: []string{"^WARN"}, FailOn: []string{"^ERROR"}},
+				"fail": {Sh: "echo ERROR: not found; echo done", FailOn: []string{"^ERROR"}, Dependencies: types.Dependencies{{Task: "warn"}}},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, logger, wf, []string{"fail"}, RunOptions{})
+		assert.EqualError(t, err, "failed tasks: [fail]")
+		assert.Contains(t, buffer.String(), "[warn] (succeeded)  warning: WARN: deprecated")
+		assert.Contains(t, buffer.String(), "[fail] (running)  done")
+		assert.Contains(t, buffer.String(), "[fail] (failed)  output matched \"^ERROR\": ERROR: not found")
+	})
+
+	t.Run("Job output matches a pattern and exits with a skip code", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"generate": {Sh: "printf 'ERROR: not found'; exit 2", FailOn: []string{"^ERROR"}, SkipExitCodes: []int{2}},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, logger, wf, []string{"generate"}, RunOptions{})
+		assert.EqualError(t, err, "failed tasks: [generate]")
+		assert.Contains(t, buffer.String(), "[generate] (failed)  output matched \"^ERROR\": ERROR: not found")
+	})
+
+	t.Run("Service output matches a pattern", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"service": {Sh: "echo ERROR: lost connection; sleep 30", Type: types.TaskTypeService, FailOn: []string{"^ERROR"}},
+			},
+		}
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := RunSubgraph(ctx, cancel, logger, wf, []string{"service"}, RunOptions{})
+			assert.EqualError(t, err, "failed tasks: [service]")
+		}()
+
+		time.Sleep(time.Second)
+		cancel()
+		wg.Wait()
+
+		assert.Contains(t, buffer.String(), "[service] (failed)  output matched \"^ERROR\": ERROR: lost connection")
+	})
+
+	t.Run("Dependency on a service starting", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"service": {
+					Command:        []string{"sleep", "30"},
+					ReadinessProbe: &types.Probe{Log: &types.LogAction{Success: "never"}},
+				},
+				"client": {Command: []string{"true"}, Dependencies: types.Dependencies{{Task: "service", Condition: "started"}}},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, logger, wf, []string{"client"}, RunOptions{})
+		assert.NoError(t, err)
+		assert.Contains(t, buffer.String(), "[service] (starting)  queuing \"client\"")
+		assert.Contains(t, buffer.String(), "[client] (succeeded)")
+	})
+
+	t.Run("Dependency on a job completing", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"test":   {Command: []string{"false"}},
+				"report": {Command: []string{"true"}, Dependencies: types.Dependencies{{Task: "test", Condition: "completed"}}},
+				"deploy": {Command: []string{"true"}, Dependencies: types.Dependencies{{Task: "test"}}},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, logger, wf, []string{"report", "deploy"}, RunOptions{})
+		assert.EqualError(t, err, "failed tasks: [test]")
+		assert.Contains(t, buffer.String(), "[report] (succeeded)")
+		assert.Contains(t, buffer.String(), "[deploy] (skipped)  dependency \"test\" failed")
+	})
+
+	t.Run("Dependency on a job failing", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"job":      {Command: []string{"true"}},
+				"reporter": {Command: []string{"true"}, Dependencies: types.Dependencies{{Task: "job", Condition: "failed"}}},
+				"notify":   {Command: []string{"true"}, Dependencies: types.Dependencies{{Task: "reporter", Condition: "succeeded"}}},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, logger, wf, []string{"notify"}, RunOptions{})
+		assert.NoError(t, err)
+		assert.Contains(t, buffer.String(), "[reporter] (skipped)  dependency \"job\" succeeded")
+		assert.Contains(t, buffer.String(), "[notify] (skipped)  dependency \"reporter\" skipped")
+	})
+
+	t.Run("Restart service with its dependencies", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"db": {
+					Sh:            "sleep 0.5; exit 1",
+					Type:          types.TaskTypeService,
+					RestartPolicy: "OnFailure",
+					RestartDelay:  &metav1.Duration{Duration: 100 * time.Millisecond},
+				},
+				"api": {
+					Command:                 []string{"sleep", "30"},
+					Type:                    types.TaskTypeService,
+					Dependencies:            types.Dependencies{{Task: "db"}},
+					RestartWithDependencies: true,
+				},
+				"web": {
+					Command:      []string{"sleep", "30"},
+					Type:         types.TaskTypeService,
+					Dependencies: types.Dependencies{{Task: "api"}},
+				},
+			},
+		}
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = RunSubgraph(ctx, cancel, logger, wf, []string{"web"}, RunOptions{})
+		}()
+
+		time.Sleep(900 * time.Millisecond)
+		cancel()
+		wg.Wait()
+
+		assert.Contains(t, buffer.String(), "[db] (failed)  stopping \"api\"")
+		assert.Contains(t, buffer.String(), "[api] (cancelled)")
+		assert.Equal(t, 2, strings.Count(buffer.String(), "[api] (running)  starting process"))
+		// web does not restart with its dependencies, but is queued again once api is running again
+		assert.Equal(t, 2, strings.Count(buffer.String(), "[web] (running)  starting process"))
+	})
+
+	t.Run("Tasks are stopped in reverse dependency order", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"db": {
+					Command: []string{"sleep", "30"},
+					Type:    types.TaskTypeService,
+				},
+				"api": {
+					Command:      []string{"sleep", "30"},
+					Type:         types.TaskTypeService,
+					Dependencies: types.Dependencies{{Task: "db"}},
+				},
+			},
+		}
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = RunSubgraph(ctx, cancel, logger, wf, []string{"api"}, RunOptions{})
+		}()
+
+		time.Sleep(500 * time.Millisecond)
+		cancel()
+		wg.Wait()
+
+		out := buffer.String()
+		assert.Contains(t, out, "[api] stopping")
+		assert.Contains(t, out, "[db] stopping")
+		assert.Less(t, strings.Index(out, "[api] stopped"), strings.Index(out, "[db] stopping"))
+	})
+
+	t.Run("Service is stopped with its stop signal after its pre-stop command", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"service": {
+					Sh:         "trap 'echo caught SIGINT; exit 0' INT; while true; do sleep 0.1; done",
+					Type:       types.TaskTypeService,
+					StopSignal: "SIGINT",
+					PreStop:    &types.ExecAction{Command: []string{"true"}},
+				},
+			},
+		}
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = RunSubgraph(ctx, cancel, logger, wf, []string{"service"}, RunOptions{})
+		}()
+
+		time.Sleep(500 * time.Millisecond)
+		cancel()
+		wg.Wait()
+
+		out := buffer.String()
+		assert.Contains(t, out, "running pre-stop command")
+		assert.Contains(t, out, "caught SIGINT")
+		assert.Less(t, strings.Index(out, "running pre-stop command"), strings.Index(out, "caught SIGINT"))
+	})
+
+	t.Run("Pre-stop command does not run when a job exits", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"job": {
+					Command: []string{"true"},
+					PreStop: &types.ExecAction{Command: []string{"true"}},
+				},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, logger, wf, []string{"job"}, RunOptions{})
+		assert.NoError(t, err)
+		assert.NotContains(t, buffer.String(), "running pre-stop command")
+	})
+
+	t.Run("Pre-stop command is part of the grace period", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		gracePeriod := int32(1)
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"service": {
+					Sh:                            "trap '' TERM; while true; do sleep 0.1; done",
+					Type:                          types.TaskTypeService,
+					TerminationGracePeriodSeconds: &gracePeriod,
+					PreStop:                       &types.ExecAction{Command: []string{"sleep", "30"}},
+				},
+			},
+		}
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = RunSubgraph(ctx, cancel, logger, wf, []string{"service"}, RunOptions{})
+		}()
+
+		time.Sleep(500 * time.Millisecond)
+		cancel()
+		stopping := time.Now()
+		wg.Wait()
+
+		assert.Less(t, time.Since(stopping), 2*time.Second)
+		assert.Contains(t, buffer.String(), "pre-stop command failed")
+	})
+
+	t.Run("Invalid stop signal", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"service": {
+					Command:    []string{"sleep", "30"},
+					Type:       types.TaskTypeService,
+					StopSignal: "SIGFOO",
+				},
+			},
+		}
+		go func() {
+			time.Sleep(time.Second)
+			cancel()
+		}()
+		err := RunSubgraph(ctx, cancel, logger, wf, []string{"service"}, RunOptions{})
+		assert.NoError(t, err)
+		assert.Contains(t, buffer.String(), `unknown stop signal "SIGFOO", must be SIGHUP, SIGINT, SIGQUIT, SIGKILL or SIGTERM, using SIGTERM`)
+	})
+
+	t.Run("Task names with slashes", func(t *testing.T) {
+		ctx, cancel, logger, _ := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"build[platform=linux/amd64]": {Sh: "echo built"},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, logger, wf, []string{"build[platform=linux/amd64]"}, RunOptions{})
+		assert.NoError(t, err)
+		data, err := os.ReadFile(filepath.Join("logs", "build%5Bplatform=linux%2Famd64%5D.log"))
+		assert.NoError(t, err)
+		assert.Equal(t, "built\n", string(data))
+	})
+
+	t.Run("Hooks run when tasks change phase", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		hook := &types.ExecAction{Command: []string{"sh", "-c", `echo "hook: $KIT_TASK $KIT_PHASE $KIT_MESSAGE"`}}
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"service": {
+					Command: []string{"sleep", "30"},
+					Type:    types.TaskTypeService,
+					Hooks:   &types.Hooks{PostStart: hook},
+				},
+				"build": {
+					Command:      []string{"true"},
+					Dependencies: types.Dependencies{{Task: "service"}},
+					Hooks:        &types.Hooks{OnSuccess: hook},
+				},
+				"test": {
+					Command:      []string{"false"},
+					Dependencies: types.Dependencies{{Task: "build"}},
+					Hooks:        &types.Hooks{OnFailure: hook},
+				},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, logger, wf, []string{"test"}, RunOptions{})
+		assert.EqualError(t, err, "failed tasks: [test]")
+
+		out := buffer.String()
+		assert.Contains(t, out, "running postStart hook")
+		assert.Contains(t, out, "hook: service running no ports to expose")
+		assert.Contains(t, out, "running onSuccess hook")
+		assert.Contains(t, out, "hook: build succeeded")
+		assert.Contains(t, out, "running onFailure hook")
+		assert.Contains(t, out, "hook: test failed exit status 1")
+
+		for name, phase := range map[string]string{"service": "running", "build": "succeeded", "test": "failed"} {
+			data, err := os.ReadFile(filepath.Join("logs", name+".log"))
+			assert.NoError(t, err)
+			assert.Contains(t, string(data), "hook: "+name+" "+phase)
+		}
+	})
+
+	t.Run("Slow hooks do not hold up dependents", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"service": {
+					Command: []string{"sleep", "30"},
+					Type:    types.TaskTypeService,
+					Hooks:   &types.Hooks{PostStart: &types.ExecAction{Command: []string{"sh", "-c", "sleep 1; echo seeded"}}},
+				},
+				"job": {
+					Command:      []string{"true"},
+					Dependencies: types.Dependencies{{Task: "service"}},
+				},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, logger, wf, []string{"job"}, RunOptions{})
+		assert.NoError(t, err)
+
+		out := buffer.String()
+		// the hook is waited for before we exit
+		assert.Contains(t, out, "seeded")
+		assert.Less(t, strings.Index(out, "[job] (running)  starting process"), strings.Index(out, "seeded"))
+	})
+
 	t.Run("Restart service by modifying watched file", func(t *testing.T) {
 		ctx, cancel, logger, buffer := setup(t)
 		defer cancel()
@@ -242,7 +1011,7 @@ sleep 30
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"service"}, nil)
+			err := RunSubgraph(ctx, cancel, logger, wf, []string{"service"}, RunOptions{})
 			assert.NoError(t, err)
 		}()
 
@@ -285,7 +1054,7 @@ echo "hello"
 				"service": {Command: []string{"sh", "-c", `
 echo "gutten tag"
 sleep 30
-`}, Dependencies: []string{"job"}, Ports: []types.Port{{}},
+`}, Dependencies: types.Dependencies{{Task: "job"}}, Ports: []types.Port{{}},
 				},
 			},
 		}
@@ -294,7 +1063,7 @@ sleep 30
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"service"}, nil)
+			err := RunSubgraph(ctx, cancel, logger, wf, []string{"service"}, RunOptions{})
 			assert.NoError(t, err)
 		}()
 
@@ -324,7 +1093,7 @@ sleep 30
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"service"}, nil)
+			err := RunSubgraph(ctx, cancel, logger, wf, []string{"service"}, RunOptions{})
 			assert.NoError(t, err)
 		}()
 
@@ -350,7 +1119,7 @@ sleep 30
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"job", "service"}, nil)
+			err := RunSubgraph(ctx, cancel, logger, wf, []string{"job", "service"}, RunOptions{})
 			assert.EqualError(t, err, "failed tasks: [job]")
 		}()
 
@@ -369,8 +1138,52 @@ sleep 30
 				"job": {Command: []string{"true"}},
 			},
 		}
-		err := RunSubgraph(ctx, cancel, 0, false, logger, wf, []string{"job"}, nil)
+		err := RunSubgraph(ctx, cancel, logger, wf, []string{"job"}, RunOptions{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Checksum", func(t *testing.T) {
+		t.Cleanup(func() { _ = os.RemoveAll(types.StateDir) })
+		dir := t.TempDir()
+		source := filepath.Join(dir, "source")
+		assert.NoError(t, os.WriteFile(source, []byte("1"), 0644))
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"job": {Sh: "echo ran >> count", WorkingDir: dir, Watch: []string{"source"}, Checksum: true},
+			},
+		}
+		run := func(t *testing.T, force bool) string {
+			ctx, cancel, logger, buffer := setup(t)
+			defer cancel()
+			err := RunSubgraph(ctx, cancel, logger, wf, []string{"job"}, RunOptions{Force: force})
+			assert.NoError(t, err)
+			return buffer.String()
+		}
+
+		assert.Contains(t, run(t, false), "running because no previous successful run")
+		assert.Contains(t, run(t, false), "[job] (skipped) up to date")
+		assert.NoError(t, os.WriteFile(source, []byte("2"), 0644))
+		assert.Contains(t, run(t, false), "running because "+source+" changed")
+		assert.Contains(t, run(t, true), "running because forced")
+
+		count, err := os.ReadFile(filepath.Join(dir, "count"))
+		assert.NoError(t, err)
+		assert.Equal(t, "ran\nran\nran\n", string(count))
+	})
+
+	t.Run("Force only logs when it overrides a skip", func(t *testing.T) {
+		ctx, cancel, logger, buffer := setup(t)
+		defer cancel()
+
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"job": {Command: []string{"true"}},
+			},
+		}
+		err := RunSubgraph(ctx, cancel, logger, wf, []string{"job"}, RunOptions{Force: true})
 		assert.NoError(t, err)
+		assert.NotContains(t, buffer.String(), "running because forced")
 	})
 }
 
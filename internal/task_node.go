@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"context"
 	"sync"
 
 	"github.com/kitproj/kit/internal/types"
@@ -11,17 +12,68 @@ type TaskNode struct {
 	task types.Task
 	// logFile is the log file path
 	logFile string
-	// the phase of the task, e.g. "pending", "waiting", "running", "stalled", "succeeded", "failed", "cancelled", "skipped"
+	// the phase of the task, e.g. "pending", "waiting", "initializing", "starting", "running", "stalled", "crashloop", "succeeded", "failed", "cancelled", "skipped"
 	Phase string `json:"phase"`
-	// the message for the task phase, e.g. "exit code 1'
+	// the message for the task phase, e.g. "exit status 1'
 	Message string `json:"message,omitempty"`
-	// cancel function
-	cancel func()
+	// the number of consecutive times the task has been restarted
+	Restarts int `json:"restarts,omitempty"`
+	// the conditions of the task's dependencies, by the name of the task, if not the default
+	Conditions map[string]string `json:"conditions,omitempty"`
+	// the file that triggered the task to be re-run, if any
+	Trigger string `json:"trigger,omitempty"`
+	// cancel function, it is set by the task's goroutine and called from others, so it is guarded by cancelMu
+	cancelFunc func()
+	cancelMu   *sync.Mutex
+	// stops the process, failing the task with the cause
+	stop context.CancelCauseFunc
 	// a mutex
 	mu *sync.Mutex
 }
 
-func (n TaskNode) blocked() bool {
+// setCancel sets the function that cancels the task
+func (n *TaskNode) setCancel(cancel func()) {
+	n.cancelMu.Lock()
+	defer n.cancelMu.Unlock()
+	n.cancelFunc = cancel
+}
+
+// cancel cancels the task
+func (n *TaskNode) cancel() {
+	n.cancelMu.Lock()
+	cancel := n.cancelFunc
+	n.cancelMu.Unlock()
+	cancel()
+}
+
+// unmet returns true if the task finished without meeting the condition of a dependency on it, so it will not meet it
+// unless it runs again
+func (n TaskNode) unmet(condition string) bool {
+	switch n.Phase {
+	case "succeeded", "skipped", "failed":
+		return n.blocked(condition)
+	default:
+		return false
+	}
+}
+
+// blocked returns true if the task does not meet the condition of a dependency on it, which defaults to "ready"
+func (n TaskNode) blocked(condition string) bool {
+	switch condition {
+	case "started":
+		switch n.Phase {
+		case "initializing", "starting", "running", "stalled", "succeeded", "skipped":
+			return false
+		default:
+			return true
+		}
+	case "succeeded":
+		return n.Phase != "succeeded" && n.Phase != "skipped"
+	case "completed":
+		return n.Phase != "succeeded" && n.Phase != "skipped" && n.Phase != "failed"
+	case "failed":
+		return n.Phase != "failed"
+	}
 	switch n.Phase {
 	case "running", "stalled":
 		return n.task.GetType() == types.TaskTypeJob
@@ -11,47 +11,79 @@ func Test_taskNode_blocked(t *testing.T) {
 	service := types.Task{Ports: []types.Port{{}}}
 	t.Run("service running", func(t *testing.T) {
 		n := TaskNode{Phase: "running", task: service}
-		assert.False(t, n.blocked())
+		assert.False(t, n.blocked(""))
 	})
 	t.Run("service waiting", func(t *testing.T) {
 		n := TaskNode{Phase: "waiting", task: service}
-		assert.True(t, n.blocked())
+		assert.True(t, n.blocked(""))
 	})
 	t.Run("service starting", func(t *testing.T) {
 		n := TaskNode{Phase: "starting", task: service}
-		assert.True(t, n.blocked())
+		assert.True(t, n.blocked(""))
 	})
 	t.Run("service succeeded", func(t *testing.T) {
 		n := TaskNode{Phase: "succeeded", task: service}
-		assert.False(t, n.blocked())
+		assert.False(t, n.blocked(""))
 	})
 	t.Run("service cancelled", func(t *testing.T) {
 		n := TaskNode{Phase: "cancelled", task: service}
-		assert.True(t, n.blocked())
+		assert.True(t, n.blocked(""))
 	})
 	t.Run("service failed", func(t *testing.T) {
 		n := TaskNode{Phase: "failed", task: service}
-		assert.True(t, n.blocked())
+		assert.True(t, n.blocked(""))
 	})
 	task := types.Task{}
 	t.Run("task running", func(t *testing.T) {
 		n := TaskNode{Phase: "running", task: task}
-		assert.True(t, n.blocked())
+		assert.True(t, n.blocked(""))
 	})
 	t.Run("task waiting", func(t *testing.T) {
 		n := TaskNode{Phase: "waiting", task: task}
-		assert.True(t, n.blocked())
+		assert.True(t, n.blocked(""))
 	})
 	t.Run("task starting", func(t *testing.T) {
 		n := TaskNode{Phase: "starting", task: task}
-		assert.True(t, n.blocked())
+		assert.True(t, n.blocked(""))
 	})
 	t.Run("task succeeded", func(t *testing.T) {
 		n := TaskNode{Phase: "succeeded", task: task}
-		assert.False(t, n.blocked())
+		assert.False(t, n.blocked(""))
 	})
 	t.Run("task failed", func(t *testing.T) {
 		n := TaskNode{Phase: "failed", task: task}
-		assert.True(t, n.blocked())
+		assert.True(t, n.blocked(""))
 	})
 }
+
+func Test_taskNode_blocked_conditions(t *testing.T) {
+	service := types.Task{Ports: []types.Port{{}}}
+	job := types.Task{}
+	tests := []struct {
+		condition string
+		task      types.Task
+		phase     string
+		blocked   bool
+	}{
+		{"started", service, "waiting", true},
+		{"started", service, "starting", false},
+		{"started", job, "running", false},
+		{"started", job, "failed", true},
+		{"ready", service, "starting", true},
+		{"ready", service, "running", false},
+		{"ready", job, "running", true},
+		{"succeeded", service, "running", true},
+		{"succeeded", job, "succeeded", false},
+		{"completed", job, "running", true},
+		{"completed", job, "failed", false},
+		{"completed", job, "succeeded", false},
+		{"failed", job, "succeeded", true},
+		{"failed", job, "failed", false},
+	}
+	for _, test := range tests {
+		t.Run(test.condition+" "+string(test.task.GetType())+" "+test.phase, func(t *testing.T) {
+			n := TaskNode{Phase: test.phase, task: test.task}
+			assert.Equal(t, test.blocked, n.blocked(test.condition))
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// StateDir is the directory the checksums of tasks' last successful runs are stored in.
+const StateDir = ".kit"
+
+// the prefix of the keys of files in the inputs, so they cannot clash with other inputs
+const filePrefix = "file:"
+
+// Inputs are checksums of everything that can change a task's output, keyed by the input, e.g. "command" or
+// "file:src/main.go".
+type Inputs map[string]string
+
+// Inputs returns the checksums of the task's inputs: the files it watches, its command, environment and image.
+func (t *Task) Inputs(spec Spec) (Inputs, error) {
+	environ, err := Environ(spec, *t)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(environ)
+	inputs := Inputs{
+		"image":      checksum(t.Image),
+		"command":    checksum(append(t.GetCommand(), t.Args...)...),
+		"env":        checksum(environ...),
+		"workingDir": checksum(t.WorkingDir),
+	}
+	matcher, err := t.Matcher()
+	if err != nil {
+		return nil, err
+	}
+	files, err := matcher.Files()
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		sum, err := fileChecksum(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to checksum %q: %w", file, err)
+		}
+		inputs[filePrefix+file] = sum
+	}
+	return inputs, nil
+}
+
+// Outdated returns why the task must be run, or "" if it can be skipped. If the inputs are nil, the task is outdated
+// if any target is older than the watched files, otherwise if the inputs differ from the last successful run.
+func (t *Task) Outdated(name string, inputs Inputs) (string, error) {
+	if inputs == nil {
+		return t.outdated(), nil
+	}
+	for _, target := range t.Targets {
+		if _, err := os.Stat(filepath.Join(t.WorkingDir, target)); err != nil {
+			return fmt.Sprintf("target %q does not exist", target), nil
+		}
+	}
+	previous, err := ReadInputs(name)
+	if err != nil {
+		return "", err
+	}
+	if previous == nil {
+		return "no previous successful run", nil
+	}
+	return inputs.diff(previous), nil
+}
+
+// diff returns a description of the first difference from the previous inputs, or "" if they are the same
+func (i Inputs) diff(previous Inputs) string {
+	var keys []string
+	for k := range i {
+		keys = append(keys, k)
+	}
+	for k := range previous {
+		if _, ok := i[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		name := strings.TrimPrefix(k, filePrefix)
+		now, ok := i[k]
+		before, existed := previous[k]
+		switch {
+		case !ok:
+			return fmt.Sprintf("%s was removed", name)
+		case !existed:
+			return fmt.Sprintf("%s was added", name)
+		case now != before:
+			return fmt.Sprintf("%s changed", name)
+		}
+	}
+	return ""
+}
+
+// ReadInputs reads the inputs of the task's last successful run, nil if there was none.
+func ReadInputs(name string) (Inputs, error) {
+	data, err := os.ReadFile(stateFile(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+	inputs := Inputs{}
+	if err := json.Unmarshal(data, &inputs); err != nil {
+		return nil, fmt.Errorf("failed to parse state: %w", err)
+	}
+	return inputs, nil
+}
+
+// WriteInputs records the inputs of the task's successful run.
+func WriteInputs(name string, inputs Inputs) error {
+	if err := os.MkdirAll(StateDir, 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	data, err := json.MarshalIndent(inputs, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(stateFile(name), data, 0644); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	return nil
+}
+
+// task names may contain slashes, so we escape them
+func stateFile(name string) string {
+	return filepath.Join(StateDir, url.PathEscape(name)+".json")
+}
+
+func checksum(values ...string) string {
+	h := sha256.New()
+	for _, v := range values {
+		// separate the values, so ["ab"] and ["a", "b"] differ
+		_, _ = io.WriteString(h, v)
+		_, _ = h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
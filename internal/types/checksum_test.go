@@ -0,0 +1,73 @@
+package types
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTask_Inputs(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "src", "pkg"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "src", "pkg", "main.go"), []byte("package main"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module foo"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("# foo"), 0644))
+
+	task := &Task{WorkingDir: dir, Command: Strings{"go", "build"}, Watch: Strings{"src", "*.mod"}}
+	inputs, err := task.Inputs(Spec{})
+	assert.NoError(t, err)
+	assert.Contains(t, inputs, "file:"+filepath.Join(dir, "src", "pkg", "main.go"))
+	assert.Contains(t, inputs, "file:"+filepath.Join(dir, "go.mod"))
+	assert.NotContains(t, inputs, "file:"+filepath.Join(dir, "README.md"))
+
+	t.Run("Unchanged", func(t *testing.T) {
+		again, err := task.Inputs(Spec{})
+		assert.NoError(t, err)
+		assert.Equal(t, "", again.diff(inputs))
+	})
+	t.Run("Command changed", func(t *testing.T) {
+		changed := *task
+		changed.Args = Strings{"-v"}
+		again, err := changed.Inputs(Spec{})
+		assert.NoError(t, err)
+		assert.Equal(t, "command changed", again.diff(inputs))
+	})
+	t.Run("Env changed", func(t *testing.T) {
+		again, err := task.Inputs(Spec{Env: EnvVars{"FOO": "1"}})
+		assert.NoError(t, err)
+		assert.Equal(t, "env changed", again.diff(inputs))
+	})
+	t.Run("File removed", func(t *testing.T) {
+		changed := *task
+		changed.Watch = Strings{"src"}
+		again, err := changed.Inputs(Spec{})
+		assert.NoError(t, err)
+		assert.Equal(t, filepath.Join(dir, "go.mod")+" was removed", again.diff(inputs))
+	})
+}
+
+func TestTask_Outdated(t *testing.T) {
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(t.TempDir()))
+	defer func() { _ = os.Chdir(wd) }()
+
+	task := &Task{Checksum: true}
+	inputs := Inputs{"command": checksum("true")}
+
+	reason, err := task.Outdated("foo:bar", inputs)
+	assert.NoError(t, err)
+	assert.Equal(t, "no previous successful run", reason)
+
+	assert.NoError(t, WriteInputs("foo:bar", inputs))
+	reason, err = task.Outdated("foo:bar", inputs)
+	assert.NoError(t, err)
+	assert.Equal(t, "", reason)
+
+	task.Targets = Strings{"missing"}
+	reason, err = task.Outdated("foo:bar", inputs)
+	assert.NoError(t, err)
+	assert.Equal(t, `target "missing" does not exist`, reason)
+}
@@ -0,0 +1,74 @@
+package types
+
+import (
+	"encoding/json"
+)
+
+// A Dependency is a task that must meet a condition before this task runs.
+type Dependency struct {
+	// The name of the task.
+	Task string `json:"task"`
+	// The condition the task must meet: "started" once its process has started, "ready" once a service is running or
+	// a job has succeeded, "succeeded", "completed" once it has succeeded or failed, or "failed". Defaults to "ready".
+	Condition string `json:"condition,omitempty"`
+}
+
+func (d *Dependency) UnmarshalJSON(data []byte) error {
+	if data[0] == '{' {
+		x := struct {
+			Task      string `json:"task"`
+			Condition string `json:"condition,omitempty"`
+		}{}
+		if err := json.Unmarshal(data, &x); err != nil {
+			return err
+		}
+		d.Task = x.Task
+		d.Condition = x.Condition
+		return nil
+	}
+	return json.Unmarshal(data, &d.Task)
+}
+
+func (d Dependency) MarshalJSON() ([]byte, error) {
+	if d.Condition == "" {
+		return json.Marshal(d.Task)
+	}
+	return json.Marshal(struct {
+		Task      string `json:"task"`
+		Condition string `json:"condition"`
+	}{d.Task, d.Condition})
+}
+
+// Dependencies is a list of dependencies, either the names of tasks or dependencies with a condition.
+type Dependencies []Dependency
+
+func (d *Dependencies) UnmarshalJSON(data []byte) error {
+	if data[0] == '[' {
+		var x []Dependency
+		if err := json.Unmarshal(data, &x); err != nil {
+			return err
+		}
+		*d = x
+		return nil
+	}
+	var names Strings
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+	var x Dependencies
+	for _, name := range names {
+		x = append(x, Dependency{Task: name})
+	}
+	*d = x
+	return nil
+}
+
+// Condition returns the condition of the dependency on the task, or "" if there is no dependency on it.
+func (d Dependencies) Condition(task string) string {
+	for _, dependency := range d {
+		if dependency.Task == task {
+			return dependency.Condition
+		}
+	}
+	return ""
+}
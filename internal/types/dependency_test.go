@@ -0,0 +1,38 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDependencies(t *testing.T) {
+	t.Run("List", func(t *testing.T) {
+		var d Dependencies
+		err := json.Unmarshal([]byte(`["build", {"task": "api", "condition": "started"}]`), &d)
+		assert.NoError(t, err)
+		assert.Equal(t, Dependencies{{Task: "build"}, {Task: "api", Condition: "started"}}, d)
+		assert.Equal(t, "started", d.Condition("api"))
+		assert.Equal(t, "", d.Condition("build"))
+
+		data, err := json.Marshal(d)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `["build", {"task": "api", "condition": "started"}]`, string(data))
+	})
+	t.Run("String", func(t *testing.T) {
+		var d Dependencies
+		err := json.Unmarshal([]byte(`"build test"`), &d)
+		assert.NoError(t, err)
+		assert.Equal(t, Dependencies{{Task: "build"}, {Task: "test"}}, d)
+	})
+	t.Run("Replaces existing", func(t *testing.T) {
+		d := Dependencies{{Task: "build"}}
+		err := json.Unmarshal([]byte(`["test"]`), &d)
+		assert.NoError(t, err)
+		assert.Equal(t, Dependencies{{Task: "test"}}, d)
+		err = json.Unmarshal([]byte(`"lint"`), &d)
+		assert.NoError(t, err)
+		assert.Equal(t, Dependencies{{Task: "lint"}}, d)
+	})
+}
@@ -0,0 +1,40 @@
+package types
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net/url"
+	"strings"
+)
+
+// ExecAction describes a command to run.
+type ExecAction struct {
+	// The command to run, in the container if the task runs one. An exit code of zero is success.
+	Command Strings `json:"command"`
+}
+
+func (a ExecAction) URL() *url.URL {
+	b := &bytes.Buffer{}
+	w := csv.NewWriter(b)
+	w.Comma = ' '
+	_ = w.Write(a.Command)
+	w.Flush()
+	return &url.URL{Scheme: "exec", Opaque: escapeOpaque(strings.TrimSuffix(b.String(), "\n"))}
+}
+
+func (a *ExecAction) Unstring(s string) error {
+	x, err := url.Parse(s)
+	if err != nil {
+		return err
+	}
+	command, err := url.PathUnescape(x.Opaque)
+	if err != nil {
+		return err
+	}
+	var c Strings
+	if err := c.Unstring(command); err != nil {
+		return err
+	}
+	a.Command = c
+	return nil
+}
@@ -0,0 +1,178 @@
+package types
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A TaskError is an error in a field of a task.
+type TaskError struct {
+	// The name of the task.
+	Task string
+	// The path to the field within the task, e.g. ["command", "0"].
+	Path []string
+	// The error.
+	Err error
+}
+
+func (e *TaskError) Error() string {
+	return fmt.Sprintf("task %q: %s: %v", e.Task, strings.Join(e.Path, "."), e.Err)
+}
+
+func (e *TaskError) Unwrap() error {
+	return e.Err
+}
+
+// ProjectName is the name of the project, the last part of the working directory.
+func ProjectName() string {
+	return filepath.Base(os.Getenv("PWD"))
+}
+
+// a variable expression, e.g. "FOO", "FOO:-bar" or "FOO:?message"
+var variableExpr = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)(?:(:[-?])(.*))?$`)
+
+// Expand replaces ${VAR}, ${VAR:-default} and ${VAR:?message} in the string with the values of the variables.
+// The default is used if the variable is unset or empty. If the variable is unset or empty, ${VAR:?message} is an error.
+// Variables that are not set and have no default are left as-is, so that shell scripts can use their own variables.
+// Use $${VAR} for a literal ${VAR}.
+func Expand(s string, vars map[string]string) (string, error) {
+	b := strings.Builder{}
+	for i := 0; i < len(s); {
+		if strings.HasPrefix(s[i:], "$${") {
+			b.WriteString("${")
+			i += 3
+			continue
+		}
+		if !strings.HasPrefix(s[i:], "${") {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+		// find the matching brace, defaults may contain variables too
+		end, depth := -1, 0
+		for j := i + 2; j < len(s) && end < 0; j++ {
+			switch s[j] {
+			case '{':
+				depth++
+			case '}':
+				if depth == 0 {
+					end = j
+				}
+				depth--
+			}
+		}
+		if end < 0 {
+			return "", fmt.Errorf("missing closing brace in %q", s[i:])
+		}
+		expr := s[i+2 : end]
+		match := variableExpr.FindStringSubmatch(expr)
+		// not a variable we understand (e.g. the shell's ${#FOO}), so leave it for the shell
+		if match == nil {
+			b.WriteString(s[i : end+1])
+			i = end + 1
+			continue
+		}
+		name, op, arg := match[1], match[2], match[3]
+		value, ok := vars[name]
+		switch {
+		case value != "":
+			b.WriteString(value)
+		case op == ":-":
+			x, err := Expand(arg, vars)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(x)
+		case op == ":?":
+			if arg == "" {
+				arg = "required variable is not set"
+			}
+			return "", fmt.Errorf("%s: %s", name, arg)
+		case ok:
+			// set, but empty
+		default:
+			b.WriteString(s[i : end+1])
+		}
+		i = end + 1
+	}
+	return b.String(), nil
+}
+
+// vars returns the variables that can be used in the spec's tasks, later variables override earlier ones
+func (s *Spec) vars() (map[string]string, error) {
+	vars := map[string]string{
+		"KIT_PROJECT": ProjectName(),
+	}
+	environ, err := s.Environ()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range append(environ, os.Environ()...) {
+		if name, value, ok := strings.Cut(e, "="); ok {
+			vars[name] = value
+		}
+	}
+	return vars, nil
+}
+
+// expand returns a copy of the task with the variables expanded
+func (t Task) expand(name string, vars map[string]string) (Task, error) {
+	var err error
+	// we return the first error, with the path to the field
+	expand := func(s string, path ...any) string {
+		if err != nil {
+			return s
+		}
+		x, e := Expand(s, vars)
+		if e != nil {
+			var p []string
+			for _, segment := range path {
+				p = append(p, fmt.Sprint(segment))
+			}
+			err = &TaskError{Task: name, Path: p, Err: e}
+		}
+		return x
+	}
+	expandStrings := func(field string, s Strings) Strings {
+		if s == nil {
+			return nil
+		}
+		x := make(Strings, len(s))
+		for i, v := range s {
+			x[i] = expand(v, field, i)
+		}
+		return x
+	}
+
+	t.Command = expandStrings("command", t.Command)
+	t.Args = expandStrings("args", t.Args)
+	t.Sh = expand(t.Sh, "sh")
+	t.Image = expand(t.Image, "image")
+	t.WorkingDir = expand(t.WorkingDir, "workingDir")
+	t.Manifests = expandStrings("manifests", t.Manifests)
+	if t.Ports != nil {
+		ports := make(Ports, len(t.Ports))
+		for i, p := range t.Ports {
+			if p.raw != "" {
+				s := expand(p.raw, "ports", i)
+				if err != nil {
+					break
+				}
+				if strings.Contains(s, "${") {
+					return t, &TaskError{Task: name, Path: []string{"ports", strconv.Itoa(i)}, Err: fmt.Errorf("variable in %q is not set", p.raw)}
+				}
+				p = Port{}
+				if e := p.Unstring(s); e != nil {
+					return t, &TaskError{Task: name, Path: []string{"ports", strconv.Itoa(i)}, Err: e}
+				}
+			}
+			ports[i] = p
+		}
+		t.Ports = ports
+	}
+	return t, err
+}
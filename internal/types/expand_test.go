@@ -0,0 +1,107 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpand(t *testing.T) {
+	vars := map[string]string{"FOO": "foo", "EMPTY": ""}
+	tests := []struct {
+		name string
+		s    string
+		want string
+		err  string
+	}{
+		{name: "No variables", s: "foo", want: "foo"},
+		{name: "Variable", s: "${FOO}-bar", want: "foo-bar"},
+		{name: "Unset variable", s: "${BAR}", want: "${BAR}"},
+		{name: "Empty variable", s: "${EMPTY}", want: ""},
+		{name: "Plain dollar", s: "$FOO", want: "$FOO"},
+		{name: "Escaped", s: "$${FOO}", want: "${FOO}"},
+		{name: "Default", s: "${BAR:-baz}", want: "baz"},
+		{name: "Default when empty", s: "${EMPTY:-baz}", want: "baz"},
+		{name: "Default not used", s: "${FOO:-baz}", want: "foo"},
+		{name: "Nested default", s: "${BAR:-${FOO}}", want: "foo"},
+		{name: "Required", s: "${FOO:?must be set}", want: "foo"},
+		{name: "Required unset", s: "${BAR:?must be set}", err: "BAR: must be set"},
+		{name: "Required no message", s: "${BAR:?}", err: "BAR: required variable is not set"},
+		{name: "Shell expression", s: "${#FOO}", want: "${#FOO}"},
+		{name: "Missing brace", s: "${FOO", err: `missing closing brace in "${FOO"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Expand(test.s, vars)
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.want, got)
+			}
+		})
+	}
+}
+
+func TestWorkflow_Effective(t *testing.T) {
+	t.Setenv("PWD", "/tmp/my-project")
+	t.Run("Expanded", func(t *testing.T) {
+		wf := &Workflow{
+			Env: EnvVars{"PORT": "8080", "TAG": "latest"},
+			Tasks: Tasks{
+				"foo": {
+					Command:    Strings{"echo", "${KIT_TASK}", "${KIT_PROJECT}"},
+					Args:       Strings{"${TAG}"},
+					Sh:         "echo ${FOO}",
+					Image:      "app:${TAG}",
+					WorkingDir: "${KIT_PROJECT}",
+					Manifests:  Strings{"${TAG}.yaml"},
+					Ports:      Ports{{raw: "${PORT}"}},
+				},
+			},
+		}
+		effective, err := wf.Effective()
+		assert.NoError(t, err)
+		task := effective.Tasks["foo"]
+		assert.Equal(t, Strings{"echo", "foo", "my-project"}, task.Command)
+		assert.Equal(t, Strings{"latest"}, task.Args)
+		assert.Equal(t, "echo ${FOO}", task.Sh)
+		assert.Equal(t, "app:latest", task.Image)
+		assert.Equal(t, "my-project", task.WorkingDir)
+		assert.Equal(t, Strings{"latest.yaml"}, task.Manifests)
+		assert.Equal(t, []uint16{8080}, task.GetHostPorts())
+		// the original is unchanged
+		assert.Equal(t, Strings{"echo", "${KIT_TASK}", "${KIT_PROJECT}"}, wf.Tasks["foo"].Command)
+	})
+	t.Run("Default from task environment", func(t *testing.T) {
+		wf := &Workflow{
+			Tasks: Tasks{
+				"serve": {Env: EnvVars{"PORT": "8080"}, Sh: "serve --port ${PORT:-3000}"},
+			},
+		}
+		effective, err := wf.Effective()
+		assert.NoError(t, err)
+		assert.Equal(t, "serve --port 8080", effective.Tasks["serve"].Sh)
+	})
+	t.Run("Required from task environment", func(t *testing.T) {
+		wf := &Workflow{
+			Tasks: Tasks{
+				"serve": {Envfile: Envfile{"testdata/task.env"}, Env: EnvVars{"TOKEN": "secret"}, Sh: "serve --token ${TOKEN:?} --baz ${BAZ:?}"},
+			},
+		}
+		effective, err := wf.Effective()
+		assert.NoError(t, err)
+		assert.Equal(t, "serve --token secret --baz 3", effective.Tasks["serve"].Sh)
+	})
+	t.Run("Errors", func(t *testing.T) {
+		wf := &Workflow{
+			Tasks: Tasks{
+				"foo": {Image: "${IMAGE:?image is required}"},
+				"bar": {Ports: Ports{{raw: "${UNSET_PORT}"}}},
+			},
+		}
+		_, err := wf.Effective()
+		assert.EqualError(t, err, `task "bar": ports.0: variable in "${UNSET_PORT}" is not set
+task "foo": image: IMAGE: image is required`)
+	})
+}
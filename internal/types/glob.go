@@ -0,0 +1,325 @@
+package types
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// A Matcher matches files against a task's watch patterns and ignore files.
+type Matcher struct {
+	// the absolute directory that patterns are relative to
+	dir string
+	// the directory as written, e.g. the task's working directory, used to format the paths returned
+	base string
+	// the watch patterns, later patterns take precedence
+	patterns []pattern
+	// the patterns of files to ignore, later patterns take precedence
+	ignores []pattern
+	// the targets are the task's outputs, so never its inputs
+	targets map[string]bool
+}
+
+// a glob pattern, where "**" matches any number of directories
+type pattern struct {
+	// the slash separated segments, e.g. ["**", "*.go"]
+	segments []string
+	// if true, the pattern excludes, rather than includes, files
+	negate bool
+	// if true, the pattern only matches directories
+	dirOnly bool
+}
+
+// Matcher returns a matcher for the task's watch patterns and ignore files. Patterns are relative to the working
+// directory. A pattern matches a path if it matches the path or any of the directories it is in, so a directory is
+// matched recursively. Patterns starting with "!" exclude paths matched by earlier patterns.
+func (t *Task) Matcher() (*Matcher, error) {
+	dir, err := filepath.Abs(t.WorkingDir)
+	if err != nil {
+		return nil, err
+	}
+	m := &Matcher{dir: dir, base: t.WorkingDir, targets: map[string]bool{}}
+	for _, source := range t.Watch {
+		negate := strings.HasPrefix(source, "!")
+		source = strings.TrimPrefix(source, "!")
+		if filepath.IsAbs(source) {
+			if source, err = filepath.Rel(dir, source); err != nil {
+				return nil, err
+			}
+		}
+		p := pattern{segments: split(filepath.ToSlash(filepath.Clean(source))), negate: negate}
+		for _, segment := range p.segments {
+			if _, err := path.Match(segment, ""); err != nil {
+				return nil, fmt.Errorf("invalid watch %q: %w", source, err)
+			}
+		}
+		m.patterns = append(m.patterns, p)
+	}
+	for _, target := range t.Targets {
+		m.targets[m.rel(filepath.Join(t.WorkingDir, target))] = true
+	}
+	for _, file := range t.IgnoreFiles {
+		ignores, err := readIgnoreFile(filepath.Join(t.WorkingDir, file))
+		if err != nil {
+			return nil, err
+		}
+		m.ignores = append(m.ignores, ignores...)
+	}
+	return m, nil
+}
+
+// readIgnoreFile reads the patterns from a file in the format of .gitignore, a missing file has no patterns
+func readIgnoreFile(name string) ([]pattern, error) {
+	f, err := os.Open(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ignore file: %w", err)
+	}
+	defer f.Close()
+	var patterns []pattern
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p := pattern{negate: strings.HasPrefix(line, "!")}
+		line = strings.TrimPrefix(line, "!")
+		p.dirOnly = strings.HasSuffix(line, "/")
+		line = strings.TrimSuffix(line, "/")
+		// a pattern with a slash is relative to the file, otherwise it matches at any depth
+		if strings.Contains(line, "/") {
+			line = strings.TrimPrefix(line, "/")
+		} else {
+			line = "**/" + line
+		}
+		p.segments = split(line)
+		patterns = append(patterns, p)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ignore file: %w", err)
+	}
+	return patterns, nil
+}
+
+// Match returns true if the path is matched by the watch patterns, and is not ignored.
+func (m *Matcher) Match(name string) bool {
+	stat, err := os.Lstat(name)
+	return m.match(m.rel(name), err == nil && stat.IsDir())
+}
+
+func (m *Matcher) match(rel string, isDir bool) bool {
+	if m.targets[rel] || m.ignored(rel, isDir) {
+		return false
+	}
+	included := false
+	for _, p := range m.patterns {
+		if p.match(rel, isDir) {
+			included = !p.negate
+		}
+	}
+	return included
+}
+
+// ignored returns true if the path is ignored by the ignore files, or is a directory we never want to watch
+func (m *Matcher) ignored(rel string, isDir bool) bool {
+	for _, segment := range split(rel) {
+		if segment == ".git" || segment == StateDir {
+			return true
+		}
+	}
+	ignored := false
+	for _, p := range m.ignores {
+		if p.match(rel, isDir) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+// excluded returns true if nothing in the directory can be matched
+func (m *Matcher) excluded(rel string) bool {
+	if m.ignored(rel, true) {
+		return true
+	}
+	excluded := false
+	for _, p := range m.patterns {
+		if p.match(rel, true) {
+			excluded = p.negate
+		}
+	}
+	return excluded
+}
+
+// Files returns the files that are matched.
+func (m *Matcher) Files() ([]string, error) {
+	var files []string
+	err := m.walk(m.roots(), func(name string, d fs.DirEntry, _ bool) {
+		if d.Type().IsRegular() && m.match(m.rel(name), false) {
+			files = append(files, name)
+		}
+	})
+	return files, err
+}
+
+// Dirs returns the directories that must be watched to be notified of changes to the matched files.
+func (m *Matcher) Dirs() ([]string, error) {
+	return m.DirsIn(m.roots()...)
+}
+
+// DirsIn returns the directories within the roots that must be watched, e.g. when a directory is created.
+func (m *Matcher) DirsIn(roots ...string) ([]string, error) {
+	var within []string
+	for _, root := range roots {
+		if m.within(root) {
+			within = append(within, root)
+		}
+	}
+	var dirs []string
+	seen := map[string]bool{}
+	err := m.walk(within, func(name string, d fs.DirEntry, root bool) {
+		// if the root is a file, we watch the directory it is in, so we see it replaced
+		if !d.IsDir() && root {
+			name = filepath.Dir(name)
+		}
+		if (d.IsDir() || root) && !seen[name] {
+			seen[name] = true
+			dirs = append(dirs, name)
+		}
+	})
+	return dirs, err
+}
+
+// roots returns the paths to walk to find the matched files, i.e. the part of each pattern before any wildcards
+func (m *Matcher) roots() []string {
+	var roots []string
+	for _, p := range m.patterns {
+		if p.negate {
+			continue
+		}
+		var segments []string
+		for _, segment := range p.segments {
+			if strings.ContainsAny(segment, "*?[") {
+				break
+			}
+			segments = append(segments, segment)
+		}
+		roots = append(roots, m.display(filepath.Join(append([]string{m.dir}, segments...)...)))
+	}
+	return roots
+}
+
+// within returns true if the path is within the root of a pattern, so may contain matches
+func (m *Matcher) within(name string) bool {
+	rel := m.rel(name)
+	for _, root := range m.roots() {
+		root := m.rel(root)
+		if root == "." || rel == root || strings.HasPrefix(rel, root+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// walk walks the roots, skipping excluded directories and missing roots, and calls f once for each path found
+func (m *Matcher) walk(roots []string, f func(name string, d fs.DirEntry, root bool)) error {
+	seen := map[string]bool{}
+	for _, root := range roots {
+		err := filepath.WalkDir(root, func(name string, d fs.DirEntry, err error) error {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if d.IsDir() && m.excluded(m.rel(name)) {
+				return filepath.SkipDir
+			}
+			// we may have already walked this directory, or seen this file, as part of another root
+			if seen[name] {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			seen[name] = true
+			f(name, d, name == root)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to walk %q: %w", root, err)
+		}
+	}
+	return nil
+}
+
+// rel returns the path relative to the matcher's directory, slash separated
+func (m *Matcher) rel(name string) string {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return filepath.ToSlash(name)
+	}
+	rel, err := filepath.Rel(m.dir, abs)
+	if err != nil {
+		return filepath.ToSlash(name)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// display returns the path joined to the directory as written, as that is how the user wrote it
+func (m *Matcher) display(name string) string {
+	rel := m.rel(name)
+	if rel == ".." || strings.HasPrefix(rel, "../") || filepath.IsAbs(rel) {
+		return name
+	}
+	return filepath.Join(m.base, filepath.FromSlash(rel))
+}
+
+// match returns true if the pattern matches the path, or any of the directories it is in
+func (p pattern) match(rel string, isDir bool) bool {
+	segments := split(rel)
+	for i := len(segments); i >= 0; i-- {
+		// only the path itself maybe a file
+		if p.dirOnly && i == len(segments) && !isDir {
+			continue
+		}
+		if matchSegments(p.segments, segments[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	ok, _ := path.Match(pattern[0], segments[0])
+	return ok && matchSegments(pattern[1:], segments[1:])
+}
+
+// split splits the slash separated path into its segments, the current directory has none
+func split(rel string) []string {
+	if rel == "." || rel == "" {
+		return nil
+	}
+	return strings.Split(rel, "/")
+}
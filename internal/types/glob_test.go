@@ -0,0 +1,92 @@
+package types
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_matchSegments(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "pkg/main.go", false},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "pkg/sub/main.go", true},
+		{"pkg/**", "pkg/sub/main.go", true},
+		{"pkg/**/main.go", "pkg/main.go", true},
+		{"pkg/**/main.go", "other/main.go", false},
+	}
+	for _, test := range tests {
+		t.Run(test.pattern+" "+test.path, func(t *testing.T) {
+			assert.Equal(t, test.match, matchSegments(split(test.pattern), split(test.path)))
+		})
+	}
+}
+
+func TestTask_Matcher(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"main.go", "main_test.go", "go.mod", "pkg/util.go", "pkg/gen/gen.go", "vendor/dep.go", "build/out.go", ".git/HEAD"} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(name), 0644))
+	}
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("# output\nbuild/\ngen.go\n"), 0644))
+
+	task := &Task{
+		WorkingDir:  dir,
+		Watch:       Strings{"**/*.go", "!**/*_test.go", "!vendor", "go.mod", "."},
+		IgnoreFiles: Strings{".gitignore", ".kitignore"},
+	}
+
+	t.Run("Files", func(t *testing.T) {
+		m, err := task.Matcher()
+		assert.NoError(t, err)
+		files, err := m.Files()
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{
+			filepath.Join(dir, ".gitignore"),
+			filepath.Join(dir, "go.mod"),
+			filepath.Join(dir, "main.go"),
+			filepath.Join(dir, "main_test.go"),
+			filepath.Join(dir, "pkg/util.go"),
+			filepath.Join(dir, "vendor/dep.go"),
+		}, files)
+	})
+	t.Run("Negation", func(t *testing.T) {
+		task := *task
+		task.Watch = Strings{"**/*.go", "!**/*_test.go", "!vendor"}
+		m, err := task.Matcher()
+		assert.NoError(t, err)
+		files, err := m.Files()
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{filepath.Join(dir, "main.go"), filepath.Join(dir, "pkg/util.go")}, files)
+		assert.True(t, m.Match(filepath.Join(dir, "pkg/new.go")))
+		assert.False(t, m.Match(filepath.Join(dir, "pkg/new_test.go")))
+	})
+	t.Run("Targets", func(t *testing.T) {
+		task := Task{WorkingDir: dir, Watch: Strings{"pkg"}, Targets: Strings{"pkg/util.go"}, IgnoreFiles: Strings{".gitignore"}}
+		m, err := task.Matcher()
+		assert.NoError(t, err)
+		files, err := m.Files()
+		assert.NoError(t, err)
+		assert.Empty(t, files)
+	})
+	t.Run("Dirs", func(t *testing.T) {
+		task := Task{WorkingDir: dir, Watch: Strings{"go.mod", "pkg"}, IgnoreFiles: Strings{".gitignore"}}
+		m, err := task.Matcher()
+		assert.NoError(t, err)
+		dirs, err := m.Dirs()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{dir, filepath.Join(dir, "pkg"), filepath.Join(dir, "pkg/gen")}, dirs)
+
+		// a new directory is only watched if it maybe matched
+		dirs, err = m.DirsIn(filepath.Join(dir, "vendor"))
+		assert.NoError(t, err)
+		assert.Empty(t, dirs)
+	})
+}
@@ -0,0 +1,65 @@
+package types
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// GRPCAction describes an action based on the gRPC health checking protocol (grpc.health.v1.Health).
+type GRPCAction struct {
+	// Host name to connect to. Defaults to localhost.
+	Host string `json:"host,omitempty"`
+	// Number of the port.
+	Port uint16 `json:"port"`
+	// The name of the service to check, e.g. `my.Service`. Defaults to the server's overall health.
+	Service string `json:"service,omitempty"`
+	// Connect using TLS.
+	TLS bool `json:"tls,omitempty"`
+	// Do not verify the server's certificate, e.g. because it is self-signed.
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+}
+
+func (a GRPCAction) URL() *url.URL {
+	u := &url.URL{Scheme: "grpc", Host: fmt.Sprintf("%s:%v", a.GetHost(), a.Port)}
+	if a.Service != "" {
+		u.Path = "/" + a.Service
+	}
+	x := url.Values{}
+	if a.TLS {
+		x.Set("tls", "true")
+	}
+	if a.InsecureSkipVerify {
+		x.Set("insecureSkipVerify", "true")
+	}
+	u.RawQuery = x.Encode()
+	return u
+}
+
+func (a *GRPCAction) Unstring(s string) error {
+	x, err := url.Parse(s)
+	if err != nil {
+		return err
+	}
+	if host := x.Hostname(); host != "localhost" {
+		a.Host = host
+	}
+	a.Port = parsePort(x.Port())
+	a.Service = strings.TrimPrefix(x.Path, "/")
+	q := x.Query()
+	a.TLS, _ = strconv.ParseBool(q.Get("tls"))
+	a.InsecureSkipVerify, _ = strconv.ParseBool(q.Get("insecureSkipVerify"))
+	return nil
+}
+
+func (a GRPCAction) GetHost() string {
+	if a.Host == "" {
+		return "localhost"
+	}
+	return a.Host
+}
+
+func (a GRPCAction) GetAddress() string {
+	return fmt.Sprintf("%s:%v", a.GetHost(), a.Port)
+}
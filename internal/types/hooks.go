@@ -0,0 +1,36 @@
+package types
+
+// Hooks are commands run on the host when a task changes phase, e.g. to seed data once a service is ready. The name of
+// the task, its phase and message are in the KIT_TASK, KIT_PHASE and KIT_MESSAGE environment variables.
+type Hooks struct {
+	// Run when the task starts running, i.e. once a service is ready, or a job is started.
+	PostStart *ExecAction `json:"postStart,omitempty"`
+	// Run when the task succeeds, e.g. to clear caches after a build.
+	OnSuccess *ExecAction `json:"onSuccess,omitempty"`
+	// Run when the task fails, e.g. to collect diagnostics.
+	OnFailure *ExecAction `json:"onFailure,omitempty"`
+}
+
+// For returns the name of the hook to run when the task changes from the previous phase to the phase, and its command,
+// or nil if there is no hook to run.
+func (h *Hooks) For(previous, phase string) (string, *ExecAction) {
+	if h == nil || phase == previous {
+		return "", nil
+	}
+	switch phase {
+	case "running":
+		// a stalled task that produces output is running again, but it has not started again
+		if previous != "stalled" && h.PostStart != nil {
+			return "postStart", h.PostStart
+		}
+	case "succeeded":
+		if h.OnSuccess != nil {
+			return "onSuccess", h.OnSuccess
+		}
+	case "failed":
+		if h.OnFailure != nil {
+			return "onFailure", h.OnFailure
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,34 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHooks_For(t *testing.T) {
+	var none *Hooks
+	_, hook := none.For("waiting", "running")
+	assert.Nil(t, hook)
+
+	hooks := &Hooks{
+		PostStart: &ExecAction{Command: []string{"seed"}},
+		OnSuccess: &ExecAction{Command: []string{"clear"}},
+		OnFailure: &ExecAction{Command: []string{"diagnose"}},
+	}
+	name, hook := hooks.For("starting", "running")
+	assert.Equal(t, "postStart", name)
+	assert.Equal(t, hooks.PostStart, hook)
+	_, hook = hooks.For("stalled", "running")
+	assert.Nil(t, hook)
+	_, hook = hooks.For("running", "running")
+	assert.Nil(t, hook)
+	name, hook = hooks.For("running", "succeeded")
+	assert.Equal(t, "onSuccess", name)
+	assert.Equal(t, hooks.OnSuccess, hook)
+	name, hook = hooks.For("running", "failed")
+	assert.Equal(t, "onFailure", name)
+	assert.Equal(t, hooks.OnFailure, hook)
+	_, hook = hooks.For("running", "cancelled")
+	assert.Nil(t, hook)
+}
@@ -11,14 +11,48 @@ import (
 type HTTPGetAction struct {
 	// Scheme to use for connecting to the host. Defaults to HTTP.
 	Scheme string `json:"scheme,omitempty"`
+	// Host name to connect to. Defaults to localhost.
+	Host string `json:"host,omitempty"`
 	// Number of the port
 	Port uint16 `json:"port,omitempty"`
 	// Path to access on the HTTP server.
 	Path string `json:"path,omitempty"`
+	// Custom headers to set in the request.
+	HTTPHeaders []HTTPHeader `json:"httpHeaders,omitempty"`
+	// The status codes that are a success, either a code (e.g. `204`), a range (e.g. `200-399`) or a class (e.g. `2xx`). Defaults to any code below 300.
+	Status StatusCodes `json:"status,omitempty"`
+	// A string the response body must contain.
+	Body string `json:"body,omitempty"`
+	// A JSONPath expression evaluated against the response body, e.g. `{.status}`. It must find a value, and if `jsonPathValue` is set, that value.
+	JSONPath string `json:"jsonPath,omitempty"`
+	// The value the JSONPath expression must find.
+	JSONPathValue string `json:"jsonPathValue,omitempty"`
+	// Do not verify the server's certificate, e.g. because it is self-signed.
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+}
+
+// HTTPHeader describes a custom header to be used in HTTP probes
+type HTTPHeader struct {
+	// The header field name.
+	Name string `json:"name"`
+	// The header field value.
+	Value string `json:"value"`
 }
 
 func (a HTTPGetAction) URL() *url.URL {
-	return &url.URL{Scheme: a.GetProto(), Host: fmt.Sprintf("localhost:%v", a.Port), Path: a.Path}
+	u := &url.URL{Scheme: a.GetProto(), Host: fmt.Sprintf("%s:%v", a.GetHost(), a.Port), Path: a.Path}
+	x := url.Values{}
+	if len(a.Status) > 0 {
+		x.Set("status", strings.Join(a.Status, ","))
+	}
+	if a.Body != "" {
+		x.Set("body", a.Body)
+	}
+	if a.InsecureSkipVerify {
+		x.Set("insecureSkipVerify", "true")
+	}
+	u.RawQuery = x.Encode()
+	return u
 }
 
 func (a *HTTPGetAction) Unstring(s string) error {
@@ -27,9 +61,18 @@ func (a *HTTPGetAction) Unstring(s string) error {
 		return err
 	}
 	a.Scheme = x.Scheme
+	if host := x.Hostname(); host != "localhost" {
+		a.Host = host
+	}
 	port, _ := strconv.ParseUint(x.Port(), 10, 16)
 	a.Port = uint16(port)
 	a.Path = x.Path
+	q := x.Query()
+	if status := q.Get("status"); status != "" {
+		a.Status = strings.Split(status, ",")
+	}
+	a.Body = q.Get("body")
+	a.InsecureSkipVerify, _ = strconv.ParseBool(q.Get("insecureSkipVerify"))
 	return nil
 }
 
@@ -40,8 +83,15 @@ func (a HTTPGetAction) GetProto() string {
 	return strings.ToLower(a.Scheme)
 }
 
+func (a HTTPGetAction) GetHost() string {
+	if a.Host == "" {
+		return "localhost"
+	}
+	return a.Host
+}
+
 func (a HTTPGetAction) GetURL() string {
-	return fmt.Sprintf("%s://localhost:%v%s", a.GetProto(), a.GetPort(), a.Path)
+	return fmt.Sprintf("%s://%s:%v%s", a.GetProto(), a.GetHost(), a.GetPort(), a.Path)
 }
 
 func (a HTTPGetAction) GetPort() uint16 {
@@ -53,3 +103,30 @@ func (a HTTPGetAction) GetPort() uint16 {
 	}
 	return 80
 }
+
+// AcceptsStatus returns true if the status code is a success.
+func (a HTTPGetAction) AcceptsStatus(code int) bool {
+	if len(a.Status) == 0 {
+		return code < 300
+	}
+	for _, s := range a.Status {
+		s = strings.TrimSpace(s)
+		if from, to, ok := strings.Cut(s, "-"); ok {
+			min, err := strconv.Atoi(from)
+			if err != nil {
+				continue
+			}
+			max, err := strconv.Atoi(to)
+			if err == nil && code >= min && code <= max {
+				return true
+			}
+		} else if len(s) == 3 && strings.HasSuffix(strings.ToLower(s), "xx") {
+			if strconv.Itoa(code/100) == s[:1] {
+				return true
+			}
+		} else if s == strconv.Itoa(code) {
+			return true
+		}
+	}
+	return false
+}
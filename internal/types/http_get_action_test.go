@@ -13,3 +13,23 @@ func TestHTTPGetAction_URL(t *testing.T) {
 	}
 	assert.Equal(t, "https://localhost:8080", a.URL().String())
 }
+
+func TestHTTPGetAction_Unstring(t *testing.T) {
+	a := HTTPGetAction{}
+	err := a.Unstring("https://example.com:8443/health?status=200-399&body=UP&insecureSkipVerify=true")
+	assert.NoError(t, err)
+	assert.Equal(t, HTTPGetAction{Scheme: "https", Host: "example.com", Port: 8443, Path: "/health", Status: StatusCodes{"200-399"}, Body: "UP", InsecureSkipVerify: true}, a)
+	assert.Equal(t, "https://example.com:8443/health?body=UP&insecureSkipVerify=true&status=200-399", a.URL().String())
+}
+
+func TestHTTPGetAction_AcceptsStatus(t *testing.T) {
+	a := HTTPGetAction{}
+	assert.True(t, a.AcceptsStatus(200))
+	assert.False(t, a.AcceptsStatus(301))
+	a.Status = StatusCodes{"204", "300-302", "4xx"}
+	assert.True(t, a.AcceptsStatus(204))
+	assert.False(t, a.AcceptsStatus(200))
+	assert.True(t, a.AcceptsStatus(301))
+	assert.True(t, a.AcceptsStatus(404))
+	assert.False(t, a.AcceptsStatus(500))
+}
@@ -0,0 +1,303 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// where a task was defined
+type source struct {
+	// the file the task was defined in
+	file string
+	// the name of the task in that file
+	name string
+}
+
+// Read reads the workflow from the file, as written, i.e. without resolving includes.
+func Read(path string) (*Workflow, error) {
+	in, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	wf := &Workflow{}
+	if err = yaml.UnmarshalStrict(in, wf); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return wf, nil
+}
+
+// Load reads the workflow from the file and merges in any included workflows.
+func Load(path string) (*Workflow, error) {
+	return load(path, false, nil)
+}
+
+// Effective returns a copy of the workflow as it will be run, with the profiles applied in order, tasks merged into the
+// templates they extend, matrix tasks expanded into a task for each cell, and variables expanded. If there are errors in
+// tasks, they are returned joined, each as a *TaskError.
+func (w *Workflow) Effective(profiles ...string) (*Workflow, error) {
+	spec := Spec(*w)
+	for _, profile := range profiles {
+		if err := spec.applyProfile(profile); err != nil {
+			return nil, err
+		}
+	}
+	spec.Profiles = nil
+	if err := spec.expandTemplates(); err != nil {
+		return nil, err
+	}
+	spec.Templates = nil
+	groups, values, err := spec.expandMatrices()
+	if err != nil {
+		return nil, err
+	}
+	vars, err := spec.vars()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get variables: %w", err)
+	}
+	var errs []error
+	tasks := Tasks{}
+	for name, t := range spec.Tasks {
+		file, _ := w.Source(name)
+		configDir, err := filepath.Abs(filepath.Dir(file))
+		if err != nil {
+			return nil, err
+		}
+		taskVars := map[string]string{}
+		for k, v := range vars {
+			taskVars[k] = v
+		}
+		// the task's environment overrides the spec's, but not the host's, as when the task is run
+		environ, err := t.Environ()
+		if err != nil {
+			// the envfile may not exist yet, e.g. it is written by another task, so we only use the env
+			environ, err = t.Env.Environ()
+			if err != nil {
+				errs = append(errs, &TaskError{Task: name, Path: []string{"env"}, Err: err})
+				continue
+			}
+		}
+		for _, e := range environ {
+			if k, v, ok := strings.Cut(e, "="); ok {
+				if _, ok := os.LookupEnv(k); !ok {
+					taskVars[k] = v
+				}
+			}
+		}
+		// the matrix's values take precedence, as they're specific to the task
+		for k, v := range values[name] {
+			taskVars[k] = v
+		}
+		taskVars["KIT_TASK"] = name
+		taskVars["KIT_CONFIG_DIR"] = configDir
+		t, err = t.expand(name, taskVars)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		tasks[name] = t
+	}
+	if len(errs) > 0 {
+		// sort, so errors are stable
+		sort.Slice(errs, func(i, j int) bool {
+			return errs[i].Error() < errs[j].Error()
+		})
+		return nil, errors.Join(errs...)
+	}
+	spec.Tasks = tasks
+	spec.groups = groups
+	effective := Workflow(spec)
+	return &effective, nil
+}
+
+// Source returns the file the task was defined in and its name in that file. The file is empty if unknown.
+func (w *Workflow) Source(task string) (string, string) {
+	src, ok := w.sources[task]
+	// the cells of a matrix task are defined by the task, e.g. "build[os=linux]" is defined by "build"
+	if i := strings.Index(task, "["); !ok && i > 0 {
+		src, ok = w.sources[task[:i]]
+	}
+	if !ok {
+		return "", task
+	}
+	return src.file, src.name
+}
+
+// load reads the workflow, if rebase is true, paths in the workflow are rebased to be relative to the current directory,
+// rather than to the file
+func load(path string, rebase bool, stack []string) (*Workflow, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for i, x := range stack {
+		if x == abs {
+			return nil, fmt.Errorf("include cycle: %s", strings.Join(append(stack[i:], abs), " -> "))
+		}
+	}
+	stack = append(stack, abs)
+
+	wf, err := Read(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(path)
+
+	if rebase {
+		// templates are local to the file, and must be expanded before the paths are rebased
+		if err := (*Spec)(wf).expandTemplates(); err != nil {
+			return nil, err
+		}
+		wf.Templates = nil
+	}
+
+	wf.sources = map[string]source{}
+	for name, t := range wf.Tasks {
+		if rebase {
+			t.rebase(dir)
+			wf.Tasks[name] = t
+		}
+		wf.sources[name] = source{file: path, name: name}
+	}
+	if rebase {
+		for i, v := range wf.Volumes {
+			wf.Volumes[i].HostPath.Path = join(dir, v.HostPath.Path)
+		}
+		for i, e := range wf.Envfile {
+			wf.Envfile[i] = join(dir, e)
+		}
+	}
+
+	// sort, so errors are stable
+	var namespaces []string
+	for namespace := range wf.Include {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	for _, namespace := range namespaces {
+		file := wf.Include[namespace]
+		included, err := load(join(dir, file), true, stack)
+		if err != nil {
+			return nil, fmt.Errorf("failed to include %q: %w", file, err)
+		}
+		if err := (*Spec)(wf).merge(namespace, included); err != nil {
+			return nil, fmt.Errorf("failed to include %q: %w", file, err)
+		}
+	}
+	wf.Include = nil
+
+	return wf, nil
+}
+
+// merge the included workflow into the spec, prefixing its tasks with the namespace
+func (s *Spec) merge(namespace string, included *Workflow) error {
+	// a profile changes the whole workflow, so it must be defined in the including file
+	if len(included.Profiles) > 0 {
+		return fmt.Errorf("profiles cannot be defined in included files, define them in the including file")
+	}
+	if s.Tasks == nil {
+		s.Tasks = Tasks{}
+	}
+	for name, t := range included.Tasks {
+		// dependencies on tasks in the same file are namespaced, others refer to tasks in other files, a dependency on
+		// the cell of a matrix task, e.g. "build[os=linux]", is on the task "build"
+		for i, dependency := range t.Dependencies {
+			task, _, _ := strings.Cut(dependency.Task, "[")
+			if _, ok := included.Tasks[task]; ok {
+				t.Dependencies[i].Task = namespace + ":" + dependency.Task
+			}
+		}
+		qualifiedName := namespace + ":" + name
+		if _, ok := s.Tasks[qualifiedName]; ok {
+			return fmt.Errorf("task %q is already defined", qualifiedName)
+		}
+		s.Tasks[qualifiedName] = t
+		s.sources[qualifiedName] = included.sources[name]
+	}
+
+	for _, v := range included.Volumes {
+		found := false
+		for _, existing := range s.Volumes {
+			if existing.Name == v.Name {
+				if existing != v {
+					return fmt.Errorf("volume %q is already defined with a different host path %q", v.Name, existing.HostPath.Path)
+				}
+				found = true
+			}
+		}
+		if !found {
+			s.Volumes = append(s.Volumes, v)
+		}
+	}
+
+	for name, seats := range included.Semaphores {
+		if existing, ok := s.Semaphores[name]; ok && existing != seats {
+			return fmt.Errorf("semaphore %q is already defined with %d seats", name, existing)
+		}
+		if s.Semaphores == nil {
+			s.Semaphores = map[string]int{}
+		}
+		s.Semaphores[name] = seats
+	}
+
+	for name, value := range included.Env {
+		if existing, ok := s.Env[name]; ok && existing != value {
+			return fmt.Errorf("env %q is already defined with value %q", name, existing)
+		}
+		if s.Env == nil {
+			s.Env = EnvVars{}
+		}
+		s.Env[name] = value
+	}
+
+	s.Envfile = append(s.Envfile, included.Envfile...)
+
+	return nil
+}
+
+// rebase makes the task's paths relative to the directory, rather than the current directory
+func (t *Task) rebase(dir string) {
+	if t.Image == "" {
+		// the envfile, manifests, watches and targets are all relative to the working directory
+		t.WorkingDir = join(dir, t.WorkingDir)
+	} else {
+		// the working directory is in the container, so we must rebase each path
+		for i, e := range t.Envfile {
+			t.Envfile[i] = join(dir, e)
+		}
+		for i, source := range t.Watch {
+			// keep the negation, e.g. "!vendor"
+			if x, ok := strings.CutPrefix(source, "!"); ok {
+				t.Watch[i] = "!" + join(dir, x)
+			} else {
+				t.Watch[i] = join(dir, source)
+			}
+		}
+		for i, file := range t.IgnoreFiles {
+			t.IgnoreFiles[i] = join(dir, file)
+		}
+		for i, target := range t.Targets {
+			t.Targets[i] = join(dir, target)
+		}
+		// the image maybe a directory containing a Dockerfile
+		if _, err := os.Stat(filepath.Join(dir, t.Image, "Dockerfile")); err == nil {
+			t.Image = join(dir, t.Image)
+		}
+	}
+	if t.Log != "" {
+		t.Log = join(dir, t.Log)
+	}
+}
+
+func join(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
@@ -0,0 +1,84 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("Include", func(t *testing.T) {
+		wf, err := Load("testdata/include/tasks.yaml")
+		assert.NoError(t, err)
+		assert.Len(t, wf.Tasks, 3)
+		assert.Nil(t, wf.Include)
+
+		build := wf.Tasks["api:build"]
+		assert.Equal(t, "testdata/include/api", build.WorkingDir)
+		assert.Equal(t, Envfile{"build.env"}, build.Envfile)
+		// templates are local to the included file
+		assert.Equal(t, "build", build.Semaphore)
+		assert.Nil(t, wf.Templates)
+
+		run := wf.Tasks["api:run"]
+		assert.Equal(t, "", run.WorkingDir)
+		assert.Equal(t, Dependencies{{Task: "api:build"}}, run.Dependencies)
+		assert.Equal(t, Strings{"testdata/include/api/src"}, run.Watch)
+
+		assert.Equal(t, Dependencies{{Task: "api:run"}}, wf.Tasks["up"].Dependencies)
+
+		assert.Equal(t, EnvVars{"FOO": "1", "BAR": "2"}, wf.Env)
+		assert.Equal(t, map[string]int{"build": 1}, wf.Semaphores)
+		assert.Equal(t, []Volume{
+			{Name: "work", HostPath: HostPath{Path: "."}},
+			{Name: "data", HostPath: HostPath{Path: "testdata/include/api/data"}},
+		}, wf.Volumes)
+
+		file, name := wf.Source("api:build")
+		assert.Equal(t, "testdata/include/api/tasks.yaml", file)
+		assert.Equal(t, "build", name)
+	})
+	t.Run("Include dependency on a matrix cell", func(t *testing.T) {
+		s := &Spec{sources: map[string]source{}}
+		included := &Workflow{
+			Tasks: Tasks{
+				"build": {Command: Strings{"go", "build"}, Matrix: map[string]Strings{"os": {"linux", "darwin"}}},
+				"test":  {Command: Strings{"go", "test"}, Dependencies: Dependencies{{Task: "build[os=linux]"}}},
+			},
+			sources: map[string]source{},
+		}
+		assert.NoError(t, s.merge("api", included))
+		assert.Equal(t, Dependencies{{Task: "api:build[os=linux]"}}, s.Tasks["api:test"].Dependencies)
+
+		wf := Workflow(*s)
+		effective, err := wf.Effective()
+		assert.NoError(t, err)
+		assert.Contains(t, effective.Tasks, "api:build[os=linux]")
+	})
+	t.Run("HTTP probe status codes", func(t *testing.T) {
+		wf, err := Load("testdata/probe.yaml")
+		assert.NoError(t, err)
+		httpGet := wf.Tasks["api"].ReadinessProbe.HTTPGet
+		assert.Equal(t, StatusCodes{"200-399", "503"}, httpGet.Status)
+		assert.True(t, httpGet.AcceptsStatus(503))
+		assert.False(t, httpGet.AcceptsStatus(404))
+	})
+	t.Run("Conflict", func(t *testing.T) {
+		_, err := Load("testdata/include/conflict.yaml")
+		assert.EqualError(t, err, `failed to include "api/tasks.yaml": env "BAR" is already defined with value "3"`)
+	})
+	t.Run("Profiles in included file", func(t *testing.T) {
+		_, err := Load("testdata/include/profiles.yaml")
+		assert.EqualError(t, err, `failed to include "profiles/tasks.yaml": profiles cannot be defined in included files, define them in the including file`)
+	})
+	t.Run("Cycle", func(t *testing.T) {
+		_, err := Load("testdata/include/cycle.yaml")
+		assert.ErrorContains(t, err, "include cycle")
+	})
+	t.Run("Read does not include", func(t *testing.T) {
+		wf, err := Read("testdata/include/tasks.yaml")
+		assert.NoError(t, err)
+		assert.Len(t, wf.Tasks, 1)
+		assert.Equal(t, map[string]string{"api": "api/tasks.yaml"}, wf.Include)
+	})
+}
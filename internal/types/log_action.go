@@ -0,0 +1,41 @@
+package types
+
+import (
+	"net/url"
+	"strings"
+)
+
+// LogAction describes patterns to match against the task's output.
+type LogAction struct {
+	// A regular expression that a line of output matches when the probe succeeds, e.g. `ready to accept connections`.
+	Success string `json:"success,omitempty"`
+	// A regular expression that a line of output matches when the probe fails, e.g. `FATAL`.
+	Failure string `json:"failure,omitempty"`
+}
+
+func (a LogAction) URL() *url.URL {
+	u := &url.URL{Scheme: "log", Opaque: escapeOpaque(a.Success)}
+	if a.Failure != "" {
+		u.RawQuery = url.Values{"failure": {a.Failure}}.Encode()
+	}
+	return u
+}
+
+func (a *LogAction) Unstring(s string) error {
+	x, err := url.Parse(s)
+	if err != nil {
+		return err
+	}
+	success, err := url.PathUnescape(x.Opaque)
+	if err != nil {
+		return err
+	}
+	a.Success = success
+	a.Failure = x.Query().Get("failure")
+	return nil
+}
+
+// escapeOpaque escapes the characters that would otherwise be part of the URL
+func escapeOpaque(s string) string {
+	return strings.NewReplacer("%", "%25", "?", "%3F").Replace(s)
+}
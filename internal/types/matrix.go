@@ -0,0 +1,120 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A cell is one combination of a matrix's values.
+type cell struct {
+	// the name of the cell, e.g. "[arch=amd64,os=linux]"
+	name string
+	// the values, e.g. {arch: amd64, os: linux}
+	values map[string]string
+}
+
+// cells returns every combination of the matrix's values, the keys are sorted, and the values are in the order written
+func (t Task) cells() ([]cell, error) {
+	var keys []string
+	for key, values := range t.Matrix {
+		if len(values) == 0 {
+			return nil, fmt.Errorf("matrix %q has no values", key)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	cells := []map[string]string{{}}
+	for _, key := range keys {
+		var next []map[string]string
+		for _, c := range cells {
+			for _, value := range t.Matrix[key] {
+				x := map[string]string{key: value}
+				for k, v := range c {
+					x[k] = v
+				}
+				next = append(next, x)
+			}
+		}
+		cells = next
+	}
+
+	var out []cell
+	for _, values := range cells {
+		var parts []string
+		for _, key := range keys {
+			parts = append(parts, key+"="+values[key])
+		}
+		out = append(out, cell{name: "[" + strings.Join(parts, ",") + "]", values: values})
+	}
+	return out, nil
+}
+
+// expandMatrices replaces each matrix task with a task for each cell, and returns the names of the cells for each
+// matrix task, and the values for each cell.
+func (s *Spec) expandMatrices() (map[string][]string, map[string]map[string]string, error) {
+	groups := map[string][]string{}
+	values := map[string]map[string]string{}
+	tasks := Tasks{}
+	for name, t := range s.Tasks {
+		if len(t.Matrix) == 0 {
+			tasks[name] = t
+			continue
+		}
+		cells, err := t.cells()
+		if err != nil {
+			return nil, nil, &TaskError{Task: name, Path: []string{"matrix"}, Err: err}
+		}
+		for _, c := range cells {
+			cellName := name + c.name
+			if _, ok := s.Tasks[cellName]; ok {
+				return nil, nil, &TaskError{Task: name, Path: []string{"matrix"}, Err: fmt.Errorf("task %q is already defined", cellName)}
+			}
+			x := t
+			x.Matrix = nil
+			x.Env = EnvVars{}
+			for k, v := range t.Env {
+				x.Env[k] = v
+			}
+			for k, v := range c.values {
+				x.Env[k] = v
+			}
+			tasks[cellName] = x
+			groups[name] = append(groups[name], cellName)
+			values[cellName] = c.values
+		}
+	}
+
+	// a dependency on a matrix task is a dependency on every cell
+	for name, t := range tasks {
+		var dependencies Dependencies
+		for _, dependency := range t.Dependencies {
+			if cells, ok := groups[dependency.Task]; ok {
+				for _, cell := range cells {
+					dependencies = append(dependencies, Dependency{Task: cell, Condition: dependency.Condition})
+				}
+			} else {
+				dependencies = append(dependencies, dependency)
+			}
+		}
+		t.Dependencies = dependencies
+		tasks[name] = t
+	}
+
+	s.Tasks = tasks
+	return groups, values, nil
+}
+
+// Resolve replaces the names of any matrix tasks with the names of each of their cells.
+func (w *Workflow) Resolve(names []string) []string {
+	var out []string
+	for _, name := range names {
+		if cells, ok := w.groups[name]; ok {
+			out = append(out, cells...)
+		} else {
+			out = append(out, name)
+		}
+	}
+	return out
+}
@@ -0,0 +1,53 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTask_cells(t *testing.T) {
+	t.Run("Combinations", func(t *testing.T) {
+		task := Task{Matrix: map[string]Strings{"os": {"linux", "darwin"}, "arch": {"amd64"}}}
+		cells, err := task.cells()
+		assert.NoError(t, err)
+		assert.Equal(t, []cell{
+			{name: "[arch=amd64,os=linux]", values: map[string]string{"arch": "amd64", "os": "linux"}},
+			{name: "[arch=amd64,os=darwin]", values: map[string]string{"arch": "amd64", "os": "darwin"}},
+		}, cells)
+	})
+	t.Run("No values", func(t *testing.T) {
+		task := Task{Matrix: map[string]Strings{"os": {}}}
+		_, err := task.cells()
+		assert.EqualError(t, err, `matrix "os" has no values`)
+	})
+}
+
+func TestWorkflow_Effective_Matrix(t *testing.T) {
+	wf := &Workflow{
+		Tasks: Tasks{
+			"build": {
+				Matrix:  map[string]Strings{"os": {"linux", "darwin"}},
+				Command: Strings{"go", "build"},
+				Env:     EnvVars{"CGO_ENABLED": "0"},
+				Args:    Strings{"-o", "bin/${os}"},
+			},
+			"test": {Dependencies: Dependencies{{Task: "build"}}},
+			"one":  {Dependencies: Dependencies{{Task: "build[os=linux]"}}},
+		},
+	}
+	effective, err := wf.Effective()
+	assert.NoError(t, err)
+	assert.Len(t, effective.Tasks, 4)
+
+	linux := effective.Tasks["build[os=linux]"]
+	assert.Nil(t, linux.Matrix)
+	assert.Equal(t, EnvVars{"CGO_ENABLED": "0", "os": "linux"}, linux.Env)
+	assert.Equal(t, Strings{"-o", "bin/linux"}, linux.Args)
+	assert.Equal(t, Strings{"-o", "bin/darwin"}, effective.Tasks["build[os=darwin]"].Args)
+
+	assert.Equal(t, Dependencies{{Task: "build[os=linux]"}, {Task: "build[os=darwin]"}}, effective.Tasks["test"].Dependencies)
+	assert.Equal(t, Dependencies{{Task: "build[os=linux]"}}, effective.Tasks["one"].Dependencies)
+
+	assert.Equal(t, []string{"build[os=linux]", "build[os=darwin]", "test"}, effective.Resolve([]string{"build", "test"}))
+}
@@ -1,8 +1,22 @@
 package types
 
+import "encoding/json"
+
 // Deprecated: only used for legacy unmarshalling.
 type NamedTask struct {
 	// The name of the task, must be unique
 	Name string `json:"name"`
 	Task
 }
+
+// Task has its own UnmarshalJSON, which would otherwise be used for the whole named task
+func (t *NamedTask) UnmarshalJSON(data []byte) error {
+	var x struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(data, &x); err != nil {
+		return err
+	}
+	t.Name = x.Name
+	return json.Unmarshal(data, &t.Task)
+}
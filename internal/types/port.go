@@ -13,6 +13,8 @@ type Port struct {
 	ContainerPort uint16 `json:"containerPort,omitempty"`
 	// The host port to route to the container port
 	HostPort uint16 `json:"hostPort,omitempty"`
+	// the port as written, if it contains variables that have not yet been expanded
+	raw string
 }
 
 func (p *Port) UnmarshalJSON(data []byte) error {
@@ -40,6 +42,11 @@ func (p Port) MarshalJSON() ([]byte, error) {
 }
 
 func (p *Port) Unstring(s string) error {
+	// we cannot parse the port until the variables are expanded
+	if strings.Contains(s, "${") {
+		p.raw = s
+		return nil
+	}
 	parts := strings.Split(s, ":")
 	containerPort, err := strconv.ParseUint(parts[0], 10, 16)
 	p.ContainerPort = uint16(containerPort)
@@ -57,6 +64,9 @@ func (p *Port) Unstring(s string) error {
 }
 
 func (p Port) String() string {
+	if p.raw != "" {
+		return p.raw
+	}
 	if p.GetHostPort() == p.ContainerPort {
 		return fmt.Sprint(p.ContainerPort)
 	}
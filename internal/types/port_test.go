@@ -22,6 +22,13 @@ func TestPort_Unstring(t *testing.T) {
 		assert.Equal(t, uint16(80), p.HostPort)
 	})
 
+	t.Run("Variable", func(t *testing.T) {
+		p := &Port{}
+		err := p.Unstring("${PORT}:80")
+		assert.NoError(t, err)
+		assert.Equal(t, "${PORT}:80", p.String())
+	})
+
 	t.Run("NoHostPort", func(t *testing.T) {
 		p := &Port{}
 		err := p.Unstring("8080")
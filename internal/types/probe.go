@@ -14,6 +14,12 @@ type Probe struct {
 	TCPSocket *TCPSocketAction `json:"tcpSocket,omitempty"`
 	// The action to perform.
 	HTTPGet *HTTPGetAction `json:"httpGet,omitempty"`
+	// The action to perform.
+	GRPC *GRPCAction `json:"grpc,omitempty"`
+	// The action to perform.
+	Exec *ExecAction `json:"exec,omitempty"`
+	// The action to perform.
+	Log *LogAction `json:"log,omitempty"`
 	// Number of seconds after the process has started before the probe is initiated.
 	InitialDelaySeconds int32 `json:"initialDelaySeconds,omitempty"`
 	// How often (in seconds) to perform the probe.
@@ -22,6 +28,9 @@ type Probe struct {
 	SuccessThreshold int32 `json:"successThreshold,omitempty"`
 	// Minimum consecutive failures for the probe to be considered failed after having succeeded.
 	FailureThreshold int32 `json:"failureThreshold,omitempty"`
+	// Number of seconds after which the probe times out. Defaults to 1 second for exec and gRPC probes, TCP and HTTP
+	// probes do not time out by default.
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
 }
 
 func (p *Probe) UnmarshalJSON(data []byte) error {
@@ -29,20 +38,28 @@ func (p *Probe) UnmarshalJSON(data []byte) error {
 		x := struct {
 			TCPSocket           *TCPSocketAction `json:"tcpSocket,omitempty"`
 			HTTPGet             *HTTPGetAction   `json:"httpGet,omitempty"`
+			GRPC                *GRPCAction      `json:"grpc,omitempty"`
+			Exec                *ExecAction      `json:"exec,omitempty"`
+			Log                 *LogAction       `json:"log,omitempty"`
 			InitialDelaySeconds int32            `json:"initialDelaySeconds,omitempty"`
 			PeriodSeconds       int32            `json:"periodSeconds,omitempty"`
 			SuccessThreshold    int32            `json:"successThreshold,omitempty"`
 			FailureThreshold    int32            `json:"failureThreshold,omitempty"`
+			TimeoutSeconds      int32            `json:"timeoutSeconds,omitempty"`
 		}{}
 		if err := json.Unmarshal(data, &x); err != nil {
 			return err
 		}
 		p.TCPSocket = x.TCPSocket
 		p.HTTPGet = x.HTTPGet
+		p.GRPC = x.GRPC
+		p.Exec = x.Exec
+		p.Log = x.Log
 		p.InitialDelaySeconds = x.InitialDelaySeconds
 		p.PeriodSeconds = x.PeriodSeconds
 		p.SuccessThreshold = x.SuccessThreshold
 		p.FailureThreshold = x.FailureThreshold
+		p.TimeoutSeconds = x.TimeoutSeconds
 		return nil
 	}
 	var s string
@@ -68,11 +85,25 @@ func (p *Probe) Unstring(s string) error {
 	port := parsePort(u.Port())
 	if u.Scheme == "tcp" {
 		p.TCPSocket = &TCPSocketAction{Port: port}
+	} else if u.Scheme == "grpc" {
+		p.GRPC = &GRPCAction{}
+		if err := p.GRPC.Unstring(s); err != nil {
+			return err
+		}
+	} else if u.Scheme == "exec" {
+		p.Exec = &ExecAction{}
+		if err := p.Exec.Unstring(s); err != nil {
+			return err
+		}
+	} else if u.Scheme == "log" {
+		p.Log = &LogAction{}
+		if err := p.Log.Unstring(s); err != nil {
+			return err
+		}
 	} else {
-		p.HTTPGet = &HTTPGetAction{
-			Scheme: u.Scheme,
-			Port:   port,
-			Path:   u.Path,
+		p.HTTPGet = &HTTPGetAction{}
+		if err := p.HTTPGet.Unstring(s); err != nil {
+			return err
 		}
 	}
 
@@ -85,6 +116,8 @@ func (p *Probe) Unstring(s string) error {
 	p.PeriodSeconds = int32(period.Seconds())
 	initialDelay, _ := time.ParseDuration(q.Get("initialDelay"))
 	p.InitialDelaySeconds = int32(initialDelay.Seconds())
+	timeout, _ := time.ParseDuration(q.Get("timeout"))
+	p.TimeoutSeconds = int32(timeout.Seconds())
 	return err
 }
 
@@ -97,10 +130,16 @@ func (p Probe) URL() *url.URL {
 	var u *url.URL
 	if p.TCPSocket != nil {
 		u = p.TCPSocket.URL()
+	} else if p.GRPC != nil {
+		u = p.GRPC.URL()
+	} else if p.Exec != nil {
+		u = p.Exec.URL()
+	} else if p.Log != nil {
+		u = p.Log.URL()
 	} else {
 		u = p.HTTPGet.URL()
 	}
-	var x = url.Values{}
+	var x = u.Query()
 	if p.InitialDelaySeconds > 0 {
 		x.Add("initialDelay", p.GetInitialDelay().String())
 	}
@@ -113,6 +152,9 @@ func (p Probe) URL() *url.URL {
 	if p.FailureThreshold > 0 {
 		x.Add("failureThreshold", fmt.Sprint(p.GetFailureThreshold()))
 	}
+	if p.TimeoutSeconds > 0 {
+		x.Add("timeout", p.GetTimeout().String())
+	}
 	u.RawQuery = x.Encode()
 	return u
 }
@@ -133,6 +175,10 @@ func (p Probe) GetPeriod() time.Duration {
 
 func (p Probe) GetFailureThreshold() int {
 	if p.FailureThreshold == 0 {
+		// a line of output that matches the failure pattern is a failure, it will not change if we wait
+		if p.Log != nil {
+			return 1
+		}
 		return 20 // 1m
 	}
 	return int(p.FailureThreshold)
@@ -144,3 +190,15 @@ func (p Probe) GetSuccessThreshold() int {
 	}
 	return int(p.SuccessThreshold)
 }
+
+// GetTimeout returns the timeout of the probe, or zero if it has none. Exec and gRPC probes time out after 1 second by
+// default, TCP and HTTP probes do not time out by default.
+func (p Probe) GetTimeout() time.Duration {
+	if p.TimeoutSeconds > 0 {
+		return time.Duration(p.TimeoutSeconds) * time.Second
+	}
+	if p.Exec != nil || p.GRPC != nil {
+		return time.Second
+	}
+	return 0
+}
@@ -2,6 +2,7 @@ package types
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -16,3 +17,42 @@ func TestProbe_String(t *testing.T) {
 
 	assert.Equal(t, "tcp://localhost:8080?initialDelay=1s", p.String())
 }
+
+func TestProbe_Exec(t *testing.T) {
+	p := Probe{}
+	err := p.Unstring("exec:pg_isready -U postgres?timeout=2s")
+	assert.NoError(t, err)
+	assert.Equal(t, &ExecAction{Command: Strings{"pg_isready", "-U", "postgres"}}, p.Exec)
+	assert.Equal(t, int32(2), p.TimeoutSeconds)
+	assert.Equal(t, "exec:pg_isready -U postgres?timeout=2s", p.String())
+
+	p = Probe{Exec: &ExecAction{Command: Strings{"sh", "-c", "test -f ready?"}}}
+	assert.Equal(t, `exec:sh -c "test -f ready%3F"`, p.String())
+	x := Probe{}
+	assert.NoError(t, x.Unstring(p.String()))
+	assert.Equal(t, p, x)
+}
+
+func TestProbe_Log(t *testing.T) {
+	p := Probe{}
+	err := p.Unstring("log:ready to accept connections?failure=FATAL")
+	assert.NoError(t, err)
+	assert.Equal(t, &LogAction{Success: "ready to accept connections", Failure: "FATAL"}, p.Log)
+	assert.Equal(t, 1, p.GetFailureThreshold())
+	assert.Equal(t, "log:ready to accept connections?failure=FATAL", p.String())
+}
+
+func TestProbe_GRPC(t *testing.T) {
+	p := Probe{}
+	err := p.Unstring("grpc://localhost:9000/my.Service?tls=true")
+	assert.NoError(t, err)
+	assert.Equal(t, &GRPCAction{Port: 9000, Service: "my.Service", TLS: true}, p.GRPC)
+	assert.Equal(t, "grpc://localhost:9000/my.Service?tls=true", p.String())
+}
+
+func TestProbe_GetTimeout(t *testing.T) {
+	assert.Equal(t, time.Second, Probe{Exec: &ExecAction{Command: Strings{"true"}}}.GetTimeout())
+	assert.Equal(t, time.Duration(0), Probe{HTTPGet: &HTTPGetAction{Port: 8080}}.GetTimeout())
+	assert.Equal(t, time.Duration(0), Probe{TCPSocket: &TCPSocketAction{Port: 8080}}.GetTimeout())
+	assert.Equal(t, 5*time.Second, Probe{TCPSocket: &TCPSocketAction{Port: 8080}, TimeoutSeconds: 5}.GetTimeout())
+}
@@ -0,0 +1,198 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A Profile changes the workflow, for example to run against a different cluster.
+type Profile struct {
+	// Environment variables to add to, or replace in, the workflow's.
+	Env EnvVars `json:"env,omitempty"`
+	// Changes to tasks, keyed by task name. Each change is merged into the task: maps (e.g. env) are merged, other fields
+	// are replaced, and a null field is removed, e.g. `manifests: null`. A null task is disabled. A task that does not
+	// exist is added.
+	Tasks map[string]TaskPatch `json:"tasks,omitempty"`
+}
+
+// A TaskPatch is a JSON merge patch for a task, nil if the task should be disabled.
+type TaskPatch map[string]any
+
+// A ProfileError is an error in a profile's change to a task.
+type ProfileError struct {
+	// The name of the profile.
+	Profile string
+	// The name of the task.
+	Task string
+	// The path to the field within the change, e.g. ["ports"], empty if unknown.
+	Path []string
+	// The error.
+	Err error
+}
+
+func (e *ProfileError) Error() string {
+	if len(e.Path) == 0 {
+		return fmt.Sprintf("profile %q: task %q: %v", e.Profile, e.Task, e.Err)
+	}
+	return fmt.Sprintf("profile %q: task %q: %s: %v", e.Profile, e.Task, strings.Join(e.Path, "."), e.Err)
+}
+
+func (e *ProfileError) Unwrap() error {
+	return e.Err
+}
+
+// applyProfile applies the named profile to the spec
+func (s *Spec) applyProfile(name string) error {
+	profile, ok := s.Profiles[name]
+	if !ok {
+		return fmt.Errorf("profile %q not found", name)
+	}
+
+	env := EnvVars{}
+	for k, v := range s.Env {
+		env[k] = v
+	}
+	for k, v := range profile.Env {
+		env[k] = v
+	}
+	s.Env = env
+
+	disabled := map[string]bool{}
+	tasks := Tasks{}
+	for k, v := range s.Tasks {
+		tasks[k] = v
+	}
+	for taskName, patch := range profile.Tasks {
+		if patch == nil {
+			disabled[taskName] = true
+			delete(tasks, taskName)
+			continue
+		}
+		t, err := tasks[taskName].patch(patch)
+		if err != nil {
+			return &ProfileError{Profile: name, Task: taskName, Path: tasks[taskName].badField(patch), Err: err}
+		}
+		tasks[taskName] = t
+	}
+
+	// nothing can depend on a disabled task
+	for taskName, t := range tasks {
+		var dependencies Dependencies
+		for _, dependency := range t.Dependencies {
+			if !disabled[dependency.Task] {
+				dependencies = append(dependencies, dependency)
+			}
+		}
+		t.Dependencies = dependencies
+		tasks[taskName] = t
+	}
+
+	s.Tasks = tasks
+	return nil
+}
+
+// patch returns a copy of the task with the JSON merge patch applied
+func (t Task) patch(patch TaskPatch) (Task, error) {
+	return t.merge(patch, false)
+}
+
+// merge returns a copy of the task with the patch merged into it, see mergePatch
+func (t Task) merge(patch map[string]any, appendLists bool) (Task, error) {
+	target, err := t.values()
+	if err != nil {
+		return t, err
+	}
+	merged := mergePatch(target, patch, appendLists)
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return t, err
+	}
+	x := Task{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode((*task)(&x)); err != nil {
+		return t, err
+	}
+	x.fields, _ = merged.(map[string]any)
+	return x, nil
+}
+
+// values returns the task as a JSON object, including the fields as written that are zero or null, which would
+// otherwise be omitted
+func (t Task) values() (map[string]any, error) {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]any
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	addWritten(values, t.fields)
+	return values, nil
+}
+
+// addWritten adds the fields as written that are missing from the values, or null, e.g. `checksum: false` or
+// `env: {FOO: null}`
+func addWritten(values, written map[string]any) {
+	for k, v := range written {
+		existing, ok := values[k]
+		if x, isMap := v.(map[string]any); isMap {
+			if y, isMap := existing.(map[string]any); isMap {
+				addWritten(y, x)
+				continue
+			}
+		}
+		if !ok || v == nil {
+			values[k] = v
+		}
+	}
+}
+
+// badField returns the path to the first field of the patch that cannot be applied on its own, or nil if there is none
+func (t Task) badField(patch TaskPatch) []string {
+	var keys []string
+	for k := range patch {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if _, err := t.patch(TaskPatch{k: patch[k]}); err != nil {
+			return []string{k}
+		}
+	}
+	return nil
+}
+
+// mergePatch applies the patch to the target, as described by RFC 7386: maps are merged, a null removes the field,
+// and other values are replaced. If appendLists is true, a list whose first item is "+" is appended to the target's list
+// instead.
+func mergePatch(target any, patch any, appendLists bool) any {
+	if l, ok := patch.([]any); ok && appendLists && len(l) > 0 && l[0] == appendMarker {
+		t, _ := target.([]any)
+		return append(append([]any{}, t...), l[1:]...)
+	}
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = map[string]any{}
+	}
+	merged := map[string]any{}
+	for k, v := range t {
+		merged[k] = v
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(merged, k)
+		} else {
+			merged[k] = mergePatch(merged[k], v, appendLists)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,73 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/yaml"
+)
+
+func TestWorkflow_Effective_Profiles(t *testing.T) {
+	wf := &Workflow{}
+	err := yaml.UnmarshalStrict([]byte(`
+env:
+  LOG_LEVEL: info
+tasks:
+  api:
+    manifests: [k8s/api.yaml]
+    env:
+      PORT: "8080"
+    dependencies: [db]
+  db:
+    image: postgres
+  test:
+    command: [go, test]
+profiles:
+  local:
+    env:
+      LOG_LEVEL: debug
+    tasks:
+      api:
+        manifests: null
+        image: api
+        env:
+          DEBUG: "true"
+        ports: ["8080"]
+      db: null
+      mock:
+        command: [mock-server]
+  broken:
+    tasks:
+      test:
+        nope: true
+`), wf)
+	assert.NoError(t, err)
+
+	t.Run("Applied", func(t *testing.T) {
+		effective, err := wf.Effective("local")
+		assert.NoError(t, err)
+		assert.Nil(t, effective.Profiles)
+		assert.Equal(t, EnvVars{"LOG_LEVEL": "debug"}, effective.Env)
+		assert.Len(t, effective.Tasks, 3)
+
+		api := effective.Tasks["api"]
+		assert.Nil(t, api.Manifests)
+		assert.Equal(t, "api", api.Image)
+		assert.Equal(t, EnvVars{"PORT": "8080", "DEBUG": "true"}, api.Env)
+		assert.Equal(t, Ports{{ContainerPort: 8080, HostPort: 8080}}, api.Ports)
+		assert.Empty(t, api.Dependencies)
+
+		assert.Equal(t, Strings{"mock-server"}, effective.Tasks["mock"].Command)
+		// the workflow is not changed
+		assert.Equal(t, EnvVars{"LOG_LEVEL": "info"}, wf.Env)
+		assert.Len(t, wf.Tasks, 3)
+	})
+	t.Run("Not found", func(t *testing.T) {
+		_, err := wf.Effective("nope")
+		assert.EqualError(t, err, `profile "nope" not found`)
+	})
+	t.Run("Unknown field", func(t *testing.T) {
+		_, err := wf.Effective("broken")
+		assert.EqualError(t, err, `profile "broken": task "test": nope: json: unknown field "nope"`)
+	})
+}
@@ -1,6 +1,10 @@
 package types
 
-import "time"
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
 
 // Task is a unit of work that should be run.
 type Spec struct {
@@ -16,6 +20,21 @@ type Spec struct {
 	Env EnvVars `json:"env,omitempty"`
 	// Environment file (e.g. .env) to use
 	Envfile Envfile `json:"envfile,omitempty"`
+	// How to watch files for tasks that do not set it: "events" (the default) or "poll".
+	WatchMode string `json:"watchMode,omitempty"`
+	// How often to check for changes when polling, for tasks that do not set it. Defaults to 1s.
+	PollInterval *metav1.Duration `json:"pollInterval,omitempty"`
+	// Include other workflow files, keyed by namespace, e.g. `api: services/api/tasks.yaml`. The included tasks are prefixed with the namespace, e.g. `api:build`.
+	// Paths in the included file are relative to that file.
+	Include map[string]string `json:"include,omitempty"`
+	// Templates are tasks that other tasks in the same file can extend, keyed by name. They are not run.
+	Templates map[string]Task `json:"templates,omitempty"`
+	// Profiles change the workflow, e.g. to run against a different cluster, keyed by name. Select them with `-P`.
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+	// where each task was defined
+	sources map[string]source
+	// the names of the cells of each matrix task
+	groups map[string][]string
 }
 
 func (s *Spec) GetTerminationGracePeriod() time.Duration {
@@ -0,0 +1,40 @@
+package types
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// A list of HTTP status codes, each a number, a range or a class.
+type StatusCodes []string
+
+func (p *StatusCodes) UnmarshalJSON(data []byte) error {
+	if data[0] == '"' {
+		var x Strings
+		if err := json.Unmarshal(data, &x); err != nil {
+			return err
+		}
+		*p = append(*p, x...)
+		return nil
+	}
+	if data[0] != '[' {
+		data = append(append([]byte{'['}, data...), ']')
+	}
+	var x []json.RawMessage
+	if err := json.Unmarshal(data, &x); err != nil {
+		return err
+	}
+	for _, item := range x {
+		var i int
+		if err := json.Unmarshal(item, &i); err == nil {
+			*p = append(*p, strconv.Itoa(i))
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(item, &s); err != nil {
+			return err
+		}
+		*p = append(*p, s)
+	}
+	return nil
+}
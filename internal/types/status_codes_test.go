@@ -0,0 +1,35 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusCodes(t *testing.T) {
+	t.Run("UnmarshalJSON", func(t *testing.T) {
+		t.Run("Number", func(t *testing.T) {
+			s := StatusCodes{}
+			err := s.UnmarshalJSON([]byte(`204`))
+			assert.NoError(t, err)
+			assert.Equal(t, StatusCodes{"204"}, s)
+		})
+		t.Run("String", func(t *testing.T) {
+			s := StatusCodes{}
+			err := s.UnmarshalJSON([]byte(`"2xx 404"`))
+			assert.NoError(t, err)
+			assert.Equal(t, StatusCodes{"2xx", "404"}, s)
+		})
+		t.Run("Mixed", func(t *testing.T) {
+			s := StatusCodes{}
+			err := s.UnmarshalJSON([]byte(`[200, "300-399", "4xx"]`))
+			assert.NoError(t, err)
+			assert.Equal(t, StatusCodes{"200", "300-399", "4xx"}, s)
+		})
+		t.Run("Invalid", func(t *testing.T) {
+			s := StatusCodes{}
+			err := s.UnmarshalJSON([]byte(`[true]`))
+			assert.Error(t, err)
+		})
+	})
+}
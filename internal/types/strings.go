@@ -25,6 +25,11 @@ func (p *Strings) UnmarshalJSON(data []byte) error {
 	if err := json.Unmarshal(data, &s); err != nil {
 		return err
 	}
+	return p.Unstring(s)
+}
+
+// Unstring splits the string on spaces, parts maybe double-quoted to include spaces.
+func (p *Strings) Unstring(s string) error {
 	r := csv.NewReader(bytes.NewBufferString(s))
 	r.Comma = ' '
 	x, err := r.Read()
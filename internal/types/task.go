@@ -1,8 +1,12 @@
 package types
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"syscall"
 	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -14,6 +18,9 @@ func (t *Task) HasMutex() bool {
 
 // A task is a container or a command to run.
 type Task struct {
+	// The name of a template to extend. The task is merged into the template: maps (e.g. env) are merged, a null removes
+	// a field, and other fields are replaced, except lists whose first item is "+", which are appended to the template's list.
+	Extends string `json:"extends,omitempty"`
 	// Type is the type of the task: "service" or "job". If omitted, if there are ports, it's a service, otherwise it's a job.
 	// This is only needed when you have service that does not listen on ports.
 	// Services are running in the background.
@@ -28,6 +35,9 @@ type Task struct {
 	LivenessProbe *Probe `json:"livenessProbe,omitempty"`
 	// A probe to check if the task is ready to serve requests. If omitted, the task is assumed to be ready if when the first port is open.
 	ReadinessProbe *Probe `json:"readinessProbe,omitempty"`
+	// A probe to check if the task has started. Liveness and readiness probes do not start until it succeeds, so slow
+	// starting tasks can have a higher failure threshold while starting. If it fails, the task is failed and may be restarted.
+	StartupProbe *Probe `json:"startupProbe,omitempty"`
 	// The command to run in the container or on the host. If both the image and the command are omitted, this is a noop.
 	Command Strings `json:"command,omitempty"`
 	// The arguments to pass to the command
@@ -52,20 +62,82 @@ type Task struct {
 	VolumeMounts []VolumeMount `json:"volumeMounts,omitempty"`
 	// Use a pseudo-TTY
 	TTY bool `json:"tty,omitempty"`
-	// A list of files to watch for changes, and restart the task if they change
+	// A list of files to watch for changes, and restart the task if they change. Directories are watched recursively.
+	// Each maybe a glob, e.g. `**/*.go`, or start with "!" to exclude files matched by earlier ones, e.g. `!**/*_test.go`.
 	Watch Strings `json:"watch,omitempty"`
+	// How long to wait for changes to watched files to stop before re-running the task. Defaults to 100ms.
+	Debounce *metav1.Duration `json:"debounce,omitempty"`
+	// How to watch files: "events" to be notified of changes by the operating system, or "poll" to check for changes
+	// every poll interval, e.g. for network filesystems and Docker volumes that do not deliver events. Defaults to the
+	// workflow's watch mode.
+	WatchMode string `json:"watchMode,omitempty"`
+	// How often to check for changes when polling. Defaults to the workflow's poll interval.
+	PollInterval *metav1.Duration `json:"pollInterval,omitempty"`
+	// Files of patterns of files not to watch, in the format of .gitignore, e.g. `[.gitignore, .kitignore]`. Missing files are ignored.
+	IgnoreFiles Strings `json:"ignoreFiles,omitempty"`
 	// A mutex to prevent multiple tasks with the same mutex from running at the same time
 	Mutex string `json:"mutex,omitempty"`
 	// A semaphore to limit the number of tasks with the same semaphore that can run at the same time
 	Semaphore string `json:"semaphore,omitempty"`
-	// A list of tasks to run before this task
-	Dependencies Strings `json:"dependencies,omitempty"`
+	// A list of tasks to run before this task. Each is the name of a task, or a task and the condition it must meet, e.g.
+	// `{task: api, condition: started}`.
+	Dependencies Dependencies `json:"dependencies,omitempty"`
+	// If true, the task is stopped when a dependency restarts, e.g. a database it connects to, and started again once the
+	// dependency meets its condition again.
+	RestartWithDependencies bool `json:"restartWithDependencies,omitempty"`
 	// A list of files this task will create. If these exist, and they're newer than the watched files, the task is skipped.
 	Targets Strings `json:"targets,omitempty"`
 	// The restart policy, e.g. Always, Never, OnFailure. Defaults depends on the type of task.
 	RestartPolicy string `json:"restartPolicy,omitempty"`
+	// How long to wait before restarting the task. It doubles after each consecutive restart, up to the max restart delay. Defaults to 3s.
+	RestartDelay *metav1.Duration `json:"restartDelay,omitempty"`
+	// The longest to wait before restarting the task. Defaults to 5m.
+	MaxRestartDelay *metav1.Duration `json:"maxRestartDelay,omitempty"`
+	// The number of consecutive restarts after which the task is failed rather than restarted. Defaults to unlimited.
+	MaxRestarts int `json:"maxRestarts,omitempty"`
+	// How long the task must run for before the restart delay and the count of consecutive restarts are reset. Defaults to 1m.
+	RestartResetAfter *metav1.Duration `json:"restartResetAfter,omitempty"`
+	// A matrix of values, the task is run once for each combination, e.g. `{os: [linux, darwin], arch: [amd64, arm64]}`.
+	// Each combination is a separate task, named e.g. `build[arch=amd64,os=linux]`, with the values set as environment variables and variables.
+	Matrix map[string]Strings `json:"matrix,omitempty"`
+	// If true, the task is skipped if the checksums of the files it watches, its command, environment and image are the
+	// same as its last successful run, and its targets exist. The checksums are stored in the .kit directory.
+	Checksum bool `json:"checksum,omitempty"`
 	// The timeout for the task to be considered stalled. If omitted, the task will be considered stalled after 30 seconds of no activity.
 	StalledTimeout *metav1.Duration `json:"stalledTimeout,omitempty"`
+	// Exit codes, other than zero, that mean the task succeeded, e.g. `[3]` for a tool that exits with 3 when there are warnings.
+	SuccessExitCodes []int `json:"successExitCodes,omitempty"`
+	// Exit codes that mean the task had nothing to do, so it is skipped, e.g. `[2]`. Downstream tasks still run.
+	SkipExitCodes []int `json:"skipExitCodes,omitempty"`
+	// Regular expressions that fail the task if a line of its output matches, e.g. `ERROR`. Jobs fail once they exit, services straight away.
+	FailOn Strings `json:"failOn,omitempty"`
+	// Regular expressions that show a line of the task's output that matches as a warning in its message, e.g. `WARN`.
+	WarnOn Strings `json:"warnOn,omitempty"`
+	// What to do when the task is stalled: "restart" the task, or "fail" it. If omitted, the task is only marked as stalled.
+	OnStalled string `json:"onStalled,omitempty"`
+	// The grace period for stopping the task, before it is killed. Defaults to the workflow's grace period.
+	TerminationGracePeriodSeconds *int32 `json:"terminationGracePeriodSeconds,omitempty"`
+	// How long the task may run for before it is stopped and failed, e.g. `10m`. If omitted, there is no timeout.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	// The signal to stop the task with, e.g. `SIGINT` or `SIGQUIT`. Defaults to `SIGTERM`, or the image's stop signal.
+	StopSignal string `json:"stopSignal,omitempty"`
+	// A command to run before the task is stopped, e.g. to flush data or deregister. It runs in the container if the
+	// task runs one, and must complete within the grace period.
+	PreStop *ExecAction `json:"preStop,omitempty"`
+	// Commands to run on the host when the task changes phase, e.g. to collect diagnostics when it fails.
+	Hooks *Hooks `json:"hooks,omitempty"`
+	// the fields as written, so a task can reset a field of the template it extends, e.g. to false or null
+	fields map[string]any
+}
+
+// task has the fields of a Task, but not its methods, so it can be unmarshalled as normal
+type task Task
+
+func (t *Task) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, (*task)(t)); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &t.fields)
 }
 
 func (t Task) IsBackground() bool {
@@ -104,6 +176,13 @@ func (t *Task) GetLivenessProbe() *Probe {
 
 }
 
+func (t *Task) GetStartupProbe() *Probe {
+	if t == nil {
+		return nil
+	}
+	return t.StartupProbe
+}
+
 func (t *Task) GetRestartPolicy() string {
 	if t.RestartPolicy != "" {
 		return t.RestartPolicy
@@ -152,19 +231,33 @@ func (t *Task) GetCommand() Strings {
 
 // Skip Determines if all the targets exist. And if they're all newer that the newest source file.
 func (t *Task) Skip() bool {
+	return t.outdated() == ""
+}
+
+// outdated returns why the task must be run based on the modification times of its targets and watched files
+func (t *Task) outdated() string {
 	// if there are no targets, we must run the task
 	if len(t.Targets) == 0 {
-		return false
+		return "no targets"
 	}
 
-	youngestSource := time.Time{}
-	for _, source := range t.Watch {
-		stat, err := os.Stat(filepath.Join(t.WorkingDir, source))
+	matcher, err := t.Matcher()
+	if err != nil {
+		return err.Error()
+	}
+	sources, err := matcher.Files()
+	if err != nil {
+		return err.Error()
+	}
+
+	youngestSource, youngestName := time.Time{}, ""
+	for _, source := range sources {
+		stat, err := os.Stat(source)
 		if err != nil {
 			continue
 		}
 		if stat.ModTime().After(youngestSource) {
-			youngestSource = stat.ModTime()
+			youngestSource, youngestName = stat.ModTime(), source
 		}
 	}
 
@@ -173,30 +266,115 @@ func (t *Task) Skip() bool {
 		stat, err := os.Stat(filepath.Join(t.WorkingDir, target))
 		// if the target does not exist, we must run the task
 		if err != nil {
-			return false
+			return fmt.Sprintf("target %q does not exist", target)
 		}
 		if stat.ModTime().Before(oldestTarget) {
 			oldestTarget = stat.ModTime()
 		}
 	}
 
-	return oldestTarget.After(youngestSource)
+	if !oldestTarget.After(youngestSource) {
+		return fmt.Sprintf("%q is newer than the targets", youngestName)
+	}
+	return ""
 }
 
 func (t *Task) GetType() TaskType {
 	if t.Type != "" {
 		return t.Type
 	}
-	if len(t.Ports) > 0 || t.LivenessProbe != nil || t.ReadinessProbe != nil {
+	if len(t.Ports) > 0 || t.LivenessProbe != nil || t.ReadinessProbe != nil || t.StartupProbe != nil {
 		return TaskTypeService
 	}
 	return TaskTypeJob
 
 }
 
+func (t *Task) GetDebounce() time.Duration {
+	if t.Debounce != nil {
+		return t.Debounce.Duration
+	}
+	return 100 * time.Millisecond
+}
+
+func (t *Task) GetWatchMode(spec Spec) string {
+	if t.WatchMode != "" {
+		return t.WatchMode
+	}
+	if spec.WatchMode != "" {
+		return spec.WatchMode
+	}
+	return "events"
+}
+
+func (t *Task) GetPollInterval(spec Spec) time.Duration {
+	if t.PollInterval != nil {
+		return t.PollInterval.Duration
+	}
+	if spec.PollInterval != nil {
+		return spec.PollInterval.Duration
+	}
+	return time.Second
+}
+
+// GetRestartDelay returns how long to wait before restarting the task, after the number of consecutive restarts.
+func (t *Task) GetRestartDelay(restarts int) time.Duration {
+	delay := 3 * time.Second
+	if t.RestartDelay != nil {
+		delay = t.RestartDelay.Duration
+	}
+	maxDelay := 5 * time.Minute
+	if t.MaxRestartDelay != nil {
+		maxDelay = t.MaxRestartDelay.Duration
+	}
+	for i := 0; i < restarts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
+
+func (t *Task) GetRestartResetAfter() time.Duration {
+	if t.RestartResetAfter != nil {
+		return t.RestartResetAfter.Duration
+	}
+	return time.Minute
+}
+
 func (t *Task) GetStalledTimeout() time.Duration {
 	if t.StalledTimeout != nil {
 		return t.StalledTimeout.Duration
 	}
 	return 30 * time.Second
 }
+
+func (t *Task) GetTerminationGracePeriod(spec Spec) time.Duration {
+	if t.TerminationGracePeriodSeconds != nil {
+		return time.Duration(*t.TerminationGracePeriodSeconds) * time.Second
+	}
+	return spec.GetTerminationGracePeriod()
+}
+
+// stopSignals are the signals a task can be stopped with.
+var stopSignals = map[string]syscall.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGINT":  syscall.SIGINT,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGKILL": syscall.SIGKILL,
+	"SIGTERM": syscall.SIGTERM,
+}
+
+// GetStopSignal returns the signal to stop the task with. The signal may be written with or without the "SIG" prefix,
+// e.g. "INT" or "SIGINT".
+func (t *Task) GetStopSignal() (syscall.Signal, error) {
+	if t.StopSignal == "" {
+		return syscall.SIGTERM, nil
+	}
+	name := strings.ToUpper(t.StopSignal)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	if signal, ok := stopSignals[name]; ok {
+		return signal, nil
+	}
+	return 0, fmt.Errorf("unknown stop signal %q, must be SIGHUP, SIGINT, SIGQUIT, SIGKILL or SIGTERM", t.StopSignal)
+}
@@ -2,10 +2,12 @@ package types
 
 import (
 	"os"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestTask_AllTargetsExist(t *testing.T) {
@@ -58,3 +60,31 @@ func TestTask_GetType(t *testing.T) {
 		assert.Equal(t, TaskTypeService, task.GetType())
 	})
 }
+
+func TestTask_GetRestartDelay(t *testing.T) {
+	task := Task{}
+	assert.Equal(t, 3*time.Second, task.GetRestartDelay(0))
+	assert.Equal(t, 12*time.Second, task.GetRestartDelay(2))
+	assert.Equal(t, 5*time.Minute, task.GetRestartDelay(100))
+	task = Task{RestartDelay: &metav1.Duration{Duration: time.Second}, MaxRestartDelay: &metav1.Duration{Duration: 10 * time.Second}}
+	assert.Equal(t, 8*time.Second, task.GetRestartDelay(3))
+	assert.Equal(t, 10*time.Second, task.GetRestartDelay(4))
+}
+
+func TestTask_GetStopSignal(t *testing.T) {
+	task := Task{}
+	signal, err := task.GetStopSignal()
+	assert.NoError(t, err)
+	assert.Equal(t, syscall.SIGTERM, signal)
+	task = Task{StopSignal: "int"}
+	signal, err = task.GetStopSignal()
+	assert.NoError(t, err)
+	assert.Equal(t, syscall.SIGINT, signal)
+	task = Task{StopSignal: "SIGQUIT"}
+	signal, err = task.GetStopSignal()
+	assert.NoError(t, err)
+	assert.Equal(t, syscall.SIGQUIT, signal)
+	task = Task{StopSignal: "SIGFOO"}
+	_, err = task.GetStopSignal()
+	assert.EqualError(t, err, `unknown stop signal "SIGFOO", must be SIGHUP, SIGINT, SIGQUIT, SIGKILL or SIGTERM`)
+}
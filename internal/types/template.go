@@ -0,0 +1,73 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// the first item of a list that should be appended to the template's list, rather than replace it
+const appendMarker = "+"
+
+// expandTemplates replaces each task with the task merged into the templates it extends
+func (s *Spec) expandTemplates() error {
+	resolved := map[string]Task{}
+	var resolve func(name string, stack []string) (Task, error)
+	resolve = func(name string, stack []string) (Task, error) {
+		if t, ok := resolved[name]; ok {
+			return t, nil
+		}
+		for i, x := range stack {
+			if x == name {
+				return Task{}, fmt.Errorf("extends cycle: %s", strings.Join(append(stack[i:], name), " -> "))
+			}
+		}
+		t, ok := s.Templates[name]
+		if !ok {
+			return Task{}, fmt.Errorf("template %q not found", name)
+		}
+		if t.Extends != "" {
+			base, err := resolve(t.Extends, append(stack, name))
+			if err != nil {
+				return Task{}, err
+			}
+			if t, err = base.inherit(t); err != nil {
+				return Task{}, err
+			}
+		}
+		resolved[name] = t
+		return t, nil
+	}
+
+	tasks := Tasks{}
+	for name, t := range s.Tasks {
+		if t.Extends != "" {
+			base, err := resolve(t.Extends, nil)
+			if err == nil {
+				t, err = base.inherit(t)
+			}
+			if err != nil {
+				return &TaskError{Task: name, Path: []string{"extends"}, Err: err}
+			}
+		}
+		tasks[name] = t
+	}
+	s.Tasks = tasks
+	return nil
+}
+
+// inherit returns the task merged into this one, see mergePatch: maps are merged, a null removes the field, and other
+// fields are replaced, unless they are lists whose first item is "+", in which case the rest of the list is appended
+func (t Task) inherit(task Task) (Task, error) {
+	override, err := task.values()
+	if err != nil {
+		return Task{}, err
+	}
+	delete(override, "extends")
+	x, err := t.merge(override, true)
+	if err != nil {
+		return Task{}, err
+	}
+	x.Extends = ""
+	delete(x.fields, "extends")
+	return x, nil
+}
@@ -0,0 +1,102 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/yaml"
+)
+
+func TestWorkflow_Effective_Templates(t *testing.T) {
+	parse := func(t *testing.T, s string) *Workflow {
+		wf := &Workflow{}
+		assert.NoError(t, yaml.UnmarshalStrict([]byte(s), wf))
+		return wf
+	}
+	t.Run("Multi-level", func(t *testing.T) {
+		wf := parse(t, `
+templates:
+  base:
+    workingDir: services
+    env:
+      LOG_LEVEL: info
+    args: [-v]
+    restartPolicy: Always
+  go:
+    extends: base
+    command: [go, run]
+    env:
+      CGO_ENABLED: "0"
+tasks:
+  api:
+    extends: go
+    env:
+      LOG_LEVEL: debug
+    args: ["+", ./api]
+  web:
+    extends: go
+    args: [./web]
+`)
+		effective, err := wf.Effective()
+		assert.NoError(t, err)
+		assert.Nil(t, effective.Templates)
+		api := effective.Tasks["api"]
+		assert.Empty(t, api.Extends)
+		assert.Equal(t, "services", api.WorkingDir)
+		assert.Equal(t, "Always", api.RestartPolicy)
+		assert.Equal(t, Strings{"go", "run"}, api.Command)
+		assert.Equal(t, EnvVars{"LOG_LEVEL": "debug", "CGO_ENABLED": "0"}, api.Env)
+		assert.Equal(t, Strings{"-v", "./api"}, api.Args)
+		assert.Equal(t, Strings{"./web"}, effective.Tasks["web"].Args)
+	})
+	t.Run("Reset", func(t *testing.T) {
+		wf := parse(t, `
+templates:
+  base:
+    workingDir: services
+    checksum: true
+    stalledTimeout: 10s
+    env:
+      LOG_LEVEL: info
+tasks:
+  api:
+    extends: base
+    command: [go, run, .]
+    workingDir: null
+    checksum: false
+    env:
+      LOG_LEVEL: null
+`)
+		effective, err := wf.Effective()
+		assert.NoError(t, err)
+		api := effective.Tasks["api"]
+		assert.Equal(t, "", api.WorkingDir)
+		assert.False(t, api.Checksum)
+		assert.Empty(t, api.Env)
+		assert.Equal(t, 10*time.Second, api.GetStalledTimeout())
+	})
+	t.Run("Cycle", func(t *testing.T) {
+		wf := parse(t, `
+templates:
+  a:
+    extends: b
+  b:
+    extends: a
+tasks:
+  foo:
+    extends: a
+`)
+		_, err := wf.Effective()
+		assert.EqualError(t, err, `task "foo": extends: extends cycle: a -> b -> a`)
+	})
+	t.Run("Not found", func(t *testing.T) {
+		wf := parse(t, `
+tasks:
+  foo:
+    extends: nope
+`)
+		_, err := wf.Effective()
+		assert.EqualError(t, err, `task "foo": extends: template "nope" not found`)
+	})
+}
@@ -29,7 +29,7 @@ func TestPod(t *testing.T) {
 	//
 	tasks := wf.Tasks["bar"]
 	assert.Equal(t, Strings{"sh", "-c", "echo bar"}, tasks.GetCommand())
-	assert.Equal(t, Strings{"baz", "qux"}, tasks.Dependencies)
+	assert.Equal(t, Dependencies{{Task: "baz"}, {Task: "qux"}}, tasks.Dependencies)
 }
 
 func TestPorts_Map(t *testing.T) {
@@ -0,0 +1,27 @@
+package util
+
+import "syscall"
+
+// filesystems that do not deliver inotify events for changes made elsewhere, e.g. on the host of a container, by their magic number
+var filesystemsWithoutEvents = map[uint32]string{
+	0x6969:     "nfs",
+	0x517b:     "smb",
+	0xff534d42: "cifs",
+	0xfe534d42: "smb2",
+	0x65735546: "fuse",
+	0x01021997: "9p",
+	0x786f4256: "vboxsf",
+	0x6a656a63: "virtiofs",
+	0x00c36400: "ceph",
+	0x5346414f: "afs",
+}
+
+// FilesystemWithoutEvents returns the type of the filesystem the path is on, if it is known not to deliver file events,
+// otherwise "".
+func FilesystemWithoutEvents(path string) string {
+	stat := syscall.Statfs_t{}
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return ""
+	}
+	return filesystemsWithoutEvents[uint32(stat.Type)]
+}
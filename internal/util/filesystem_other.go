@@ -0,0 +1,9 @@
+//go:build !linux
+
+package util
+
+// FilesystemWithoutEvents returns the type of the filesystem the path is on, if it is known not to deliver file events,
+// otherwise "". We only know this on Linux.
+func FilesystemWithoutEvents(path string) string {
+	return ""
+}
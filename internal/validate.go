@@ -0,0 +1,343 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kitproj/kit/internal/types"
+	"gopkg.in/yaml.v3"
+)
+
+// A Problem is something wrong with a workflow, that would otherwise only be found part-way through a run.
+type Problem struct {
+	// The file the problem was found in.
+	File string `json:"file"`
+	// The line of the problem, zero if unknown.
+	Line int `json:"line,omitempty"`
+	// The column of the problem, zero if unknown.
+	Column int `json:"column,omitempty"`
+	// Either "error" or "warning".
+	Severity string `json:"severity"`
+	// The path to the field, e.g. "tasks.foo.volumeMounts[0].name".
+	Path string `json:"path,omitempty"`
+	// A description of the problem.
+	Message string `json:"message"`
+	// the path as segments, so we can locate it in the YAML
+	path []string
+}
+
+func (p Problem) String() string {
+	position := p.File
+	if p.Line > 0 {
+		position = fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+	}
+	if p.Path == "" {
+		return fmt.Sprintf("%s: %s: %s", position, p.Severity, p.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s: %s", position, p.Severity, p.Path, p.Message)
+}
+
+func newProblem(severity string, message string, path ...string) Problem {
+	return Problem{Severity: severity, Path: formatPath(path), Message: message, path: path}
+}
+
+// formatPath formats the path as it would be written in JavaScript, e.g. tasks.foo.ports[0]
+func formatPath(path []string) string {
+	s := ""
+	for _, segment := range path {
+		if _, err := strconv.Atoi(segment); err == nil {
+			s += "[" + segment + "]"
+		} else if s == "" {
+			s = segment
+		} else {
+			s += "." + segment
+		}
+	}
+	return s
+}
+
+// ValidateFile reads the workflow from the file, applies the profiles, validates it, and returns the problems found, in
+// the order they appear in the file.
+func ValidateFile(configFile string, profiles ...string) []Problem {
+	raw, err := types.Load(configFile)
+	if err != nil {
+		return []Problem{{File: configFile, Severity: "error", Message: err.Error()}}
+	}
+	var problems []Problem
+	wf, err := raw.Effective(profiles...)
+	if err != nil {
+		problems = taskErrorProblems(err)
+	} else {
+		problems = Validate(wf)
+	}
+	// tasks maybe defined in included files, so we parse each file once to find the positions
+	roots := map[string]*yaml.Node{}
+	for i, p := range problems {
+		file, path := configFile, p.path
+		if len(path) > 1 && path[0] == "tasks" {
+			src, name := raw.Source(path[1])
+			if src != "" {
+				file = src
+			}
+			path = append([]string{"tasks", name}, path[2:]...)
+		}
+		problems[i].File = file
+		root, ok := roots[file]
+		if !ok {
+			root = &yaml.Node{}
+			// we've already parsed the file, so this should not fail, but if it did we'd just not have positions
+			data, _ := os.ReadFile(file)
+			_ = yaml.Unmarshal(data, root)
+			roots[file] = root
+		}
+		if node := locate(root, path); node != nil {
+			problems[i].Line = node.Line
+			problems[i].Column = node.Column
+		}
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		// the config file first, then any included files
+		if a, b := problems[i].File, problems[j].File; a != b {
+			return a == configFile || (b != configFile && a < b)
+		}
+		return problems[i].Line < problems[j].Line
+	})
+	return problems
+}
+
+// taskErrorProblems converts the task errors, e.g. variables that cannot be expanded, to problems
+func taskErrorProblems(err error) []Problem {
+	var errs []error
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	} else {
+		errs = []error{err}
+	}
+	var problems []Problem
+	for _, err := range errs {
+		var taskErr *types.TaskError
+		var profileErr *types.ProfileError
+		if errors.As(err, &taskErr) {
+			problems = append(problems, newProblem("error", taskErr.Err.Error(), append([]string{"tasks", taskErr.Task}, taskErr.Path...)...))
+		} else if errors.As(err, &profileErr) {
+			problems = append(problems, newProblem("error", profileErr.Err.Error(), append([]string{"profiles", profileErr.Profile, "tasks", profileErr.Task}, profileErr.Path...)...))
+		} else {
+			problems = append(problems, Problem{Severity: "error", Message: err.Error()})
+		}
+	}
+	return problems
+}
+
+// Validate checks the workflow for problems beyond its syntax, e.g. a volume mount that names a volume that does not exist.
+func Validate(wf *types.Workflow) []Problem {
+	var problems []Problem
+
+	var names []string
+	for name := range wf.Tasks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	volumes := map[string]bool{}
+	for _, volume := range wf.Volumes {
+		volumes[volume.Name] = true
+	}
+
+	dag := NewDAG[bool]("")
+	for name := range wf.Tasks {
+		dag.AddNode(name, true)
+	}
+
+	hostPorts := map[uint16]string{}
+
+	if !validWatchMode(wf.WatchMode) {
+		problems = append(problems, newProblem("error", fmt.Sprintf("unknown watch mode %q, must be events or poll", wf.WatchMode), "watchMode"))
+	}
+
+	for _, name := range names {
+		t := wf.Tasks[name]
+		path := func(segments ...any) []string {
+			path := []string{"tasks", name}
+			for _, segment := range segments {
+				path = append(path, fmt.Sprint(segment))
+			}
+			return path
+		}
+
+		for i, mount := range t.VolumeMounts {
+			if !volumes[mount.Name] {
+				problems = append(problems, newProblem("error", fmt.Sprintf("volume %q is not defined", mount.Name), path("volumeMounts", i, "name")...))
+			}
+		}
+
+		if t.Semaphore != "" {
+			if _, ok := wf.Semaphores[t.Semaphore]; !ok {
+				problems = append(problems, newProblem("error", fmt.Sprintf("semaphore %q is not defined", t.Semaphore), path("semaphore")...))
+			}
+		}
+
+		for i, port := range t.GetHostPorts() {
+			if other, ok := hostPorts[port]; ok {
+				problems = append(problems, newProblem("error", fmt.Sprintf("host port %d is already bound by task %q", port, other), path("ports", i)...))
+			} else {
+				hostPorts[port] = name
+			}
+		}
+
+		if len(t.Targets) > 0 && len(t.Watch) == 0 && !t.Checksum {
+			problems = append(problems, newProblem("warning", "targets without watch, the task will be skipped whenever the targets exist", path("targets")...))
+		}
+
+		if !validWatchMode(t.WatchMode) {
+			problems = append(problems, newProblem("error", fmt.Sprintf("unknown watch mode %q, must be events or poll", t.WatchMode), path("watchMode")...))
+		}
+
+		for i, pattern := range t.FailOn {
+			if _, err := regexp.Compile(pattern); err != nil {
+				problems = append(problems, newProblem("error", fmt.Sprintf("invalid pattern: %v", err), path("failOn", i)...))
+			}
+		}
+		for i, pattern := range t.WarnOn {
+			if _, err := regexp.Compile(pattern); err != nil {
+				problems = append(problems, newProblem("error", fmt.Sprintf("invalid pattern: %v", err), path("warnOn", i)...))
+			}
+		}
+
+		for i, code := range t.SkipExitCodes {
+			if slices.Contains(t.SuccessExitCodes, code) {
+				problems = append(problems, newProblem("error", fmt.Sprintf("exit code %d is both a success and a skip exit code", code), path("skipExitCodes", i)...))
+			}
+		}
+
+		if t.OnStalled != "" && t.OnStalled != "restart" && t.OnStalled != "fail" {
+			problems = append(problems, newProblem("error", fmt.Sprintf("unknown stalled action %q, must be restart or fail", t.OnStalled), path("onStalled")...))
+		}
+
+		if _, err := t.GetStopSignal(); err != nil {
+			problems = append(problems, newProblem("error", err.Error(), path("stopSignal")...))
+		}
+
+		if len(t.Command) > 0 && t.Sh != "" {
+			problems = append(problems, newProblem("error", "both command and sh are set, sh would be ignored", path("sh")...))
+		}
+		if len(t.Manifests) > 0 {
+			if t.Image != "" {
+				problems = append(problems, newProblem("error", "both image and manifests are set, manifests would be ignored", path("manifests")...))
+			} else if len(t.GetCommand()) > 0 {
+				problems = append(problems, newProblem("error", "both a command and manifests are set, manifests would be ignored", path("manifests")...))
+			}
+		}
+
+		for i, dependency := range t.Dependencies {
+			if dependency.Task == name {
+				problems = append(problems, newProblem("error", "task depends on itself", path("dependencies", i)...))
+			} else if _, ok := wf.Tasks[dependency.Task]; !ok {
+				problems = append(problems, newProblem("error", fmt.Sprintf("task %q is not defined", dependency.Task), path("dependencies", i)...))
+			} else {
+				dag.AddEdge(dependency.Task, name)
+			}
+			if !validCondition(dependency.Condition) {
+				problems = append(problems, newProblem("error", fmt.Sprintf("unknown condition %q, must be started, ready, succeeded, completed or failed", dependency.Condition), path("dependencies", i, "condition")...))
+			}
+		}
+	}
+
+	for _, cycle := range dag.Cycles() {
+		problems = append(problems, newProblem("error", fmt.Sprintf("dependency cycle: %s", strings.Join(cycle, " -> ")), "tasks", cycle[0], "dependencies"))
+	}
+
+	return problems
+}
+
+func validCondition(condition string) bool {
+	switch condition {
+	case "", "started", "ready", "succeeded", "completed", "failed":
+		return true
+	}
+	return false
+}
+
+func validWatchMode(mode string) bool {
+	return mode == "" || mode == "events" || mode == "poll"
+}
+
+// WriteProblems writes the problems in the format, either "text" or "json".
+func WriteProblems(w io.Writer, problems []Problem, format string) error {
+	switch format {
+	case "text":
+		for _, p := range problems {
+			if _, err := fmt.Fprintln(w, p.String()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "json":
+		if problems == nil {
+			problems = []Problem{}
+		}
+		data, err := json.MarshalIndent(problems, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// locate finds the YAML node for the path, or the closest node to it if the path does not exist
+func locate(root *yaml.Node, path []string) *yaml.Node {
+	node := root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	// the legacy format nests the workflow in "spec"
+	if spec := child(node, "spec"); spec != nil {
+		node = spec
+	}
+	for _, segment := range path {
+		next := child(node, segment)
+		if next == nil {
+			break
+		}
+		node = next
+	}
+	return node
+}
+
+func child(node *yaml.Node, name string) *yaml.Node {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == name {
+				return node.Content[i+1]
+			}
+		}
+	case yaml.SequenceNode:
+		if i, err := strconv.Atoi(name); err == nil {
+			if i < len(node.Content) {
+				return node.Content[i]
+			}
+			return nil
+		}
+		// the legacy format has a list of named tasks
+		for _, item := range node.Content {
+			if n := child(item, "name"); n != nil && n.Value == name {
+				return item
+			}
+		}
+	}
+	return nil
+}
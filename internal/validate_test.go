@@ -0,0 +1,183 @@
+package internal
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kitproj/kit/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		wf := &types.Workflow{
+			Semaphores: map[string]int{"sema": 1},
+			Volumes:    []types.Volume{{Name: "work"}},
+			Tasks: map[string]types.Task{
+				"build":   {Command: []string{"go", "build"}, Watch: []string{"."}, Targets: []string{"kit"}},
+				"service": {Image: "httpd", Ports: []types.Port{{ContainerPort: 80, HostPort: 8080}}, VolumeMounts: []types.VolumeMount{{Name: "work"}}, Semaphore: "sema", Dependencies: types.Dependencies{{Task: "build"}}},
+			},
+		}
+		assert.Empty(t, Validate(wf))
+	})
+	t.Run("Targets without watch", func(t *testing.T) {
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"build": {Command: []string{"go", "build"}, Targets: []string{"kit"}},
+			},
+		}
+		problems := Validate(wf)
+		if assert.Len(t, problems, 1) {
+			assert.Equal(t, "warning", problems[0].Severity)
+			assert.Equal(t, "tasks.build.targets", problems[0].Path)
+		}
+	})
+	t.Run("Watch mode", func(t *testing.T) {
+		wf := &types.Workflow{
+			WatchMode: "poll",
+			Tasks: map[string]types.Task{
+				"build": {Command: []string{"go", "build"}, WatchMode: "inotify"},
+			},
+		}
+		problems := Validate(wf)
+		if assert.Len(t, problems, 1) {
+			assert.Equal(t, "tasks.build.watchMode", problems[0].Path)
+			assert.Equal(t, `unknown watch mode "inotify", must be events or poll`, problems[0].Message)
+		}
+	})
+	t.Run("Stalled action", func(t *testing.T) {
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"test": {Command: []string{"go", "test"}, OnStalled: "kill"},
+			},
+		}
+		problems := Validate(wf)
+		if assert.Len(t, problems, 1) {
+			assert.Equal(t, "tasks.test.onStalled", problems[0].Path)
+			assert.Equal(t, `unknown stalled action "kill", must be restart or fail`, problems[0].Message)
+		}
+	})
+	t.Run("Stop signal", func(t *testing.T) {
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"web": {Command: []string{"npm", "start"}, StopSignal: "SIGSTOP"},
+			},
+		}
+		problems := Validate(wf)
+		if assert.Len(t, problems, 1) {
+			assert.Equal(t, "tasks.web.stopSignal", problems[0].Path)
+			assert.Equal(t, `unknown stop signal "SIGSTOP", must be SIGHUP, SIGINT, SIGQUIT, SIGKILL or SIGTERM`, problems[0].Message)
+		}
+	})
+	t.Run("Exit codes", func(t *testing.T) {
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"lint": {Command: []string{"lint"}, SuccessExitCodes: []int{2, 3}, SkipExitCodes: []int{2}},
+			},
+		}
+		problems := Validate(wf)
+		if assert.Len(t, problems, 1) {
+			assert.Equal(t, "tasks.lint.skipExitCodes[0]", problems[0].Path)
+			assert.Equal(t, "exit code 2 is both a success and a skip exit code", problems[0].Message)
+		}
+	})
+	t.Run("Output patterns", func(t *testing.T) {
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"legacy": {Command: []string{"./legacy.sh"}, FailOn: []string{"ERROR", "(FATAL"}},
+			},
+		}
+		problems := Validate(wf)
+		if assert.Len(t, problems, 1) {
+			assert.Equal(t, "tasks.legacy.failOn[1]", problems[0].Path)
+			assert.Equal(t, "invalid pattern: error parsing regexp: missing closing ): `(FATAL`", problems[0].Message)
+		}
+	})
+	t.Run("Dependency condition", func(t *testing.T) {
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"build": {Command: []string{"go", "build"}},
+				"test":  {Command: []string{"go", "test"}, Dependencies: types.Dependencies{{Task: "build", Condition: "finished"}}},
+			},
+		}
+		problems := Validate(wf)
+		if assert.Len(t, problems, 1) {
+			assert.Equal(t, "tasks.test.dependencies[0].condition", problems[0].Path)
+			assert.Equal(t, `unknown condition "finished", must be started, ready, succeeded, completed or failed`, problems[0].Message)
+		}
+	})
+	t.Run("Command and manifests", func(t *testing.T) {
+		wf := &types.Workflow{
+			Tasks: map[string]types.Task{
+				"deploy": {Sh: "kubectl apply -f .", Manifests: []string{"."}},
+			},
+		}
+		problems := Validate(wf)
+		if assert.Len(t, problems, 1) {
+			assert.Equal(t, "both a command and manifests are set, manifests would be ignored", problems[0].Message)
+		}
+	})
+}
+
+func TestValidateFile(t *testing.T) {
+	problems := ValidateFile("testdata/invalid.yaml")
+	var lines []string
+	for _, p := range problems {
+		lines = append(lines, p.String())
+	}
+	assert.Equal(t, []string{
+		`testdata/invalid.yaml:10:9: error: tasks.foo.sh: both command and sh are set, sh would be ignored`,
+		`testdata/invalid.yaml:11:13: error: tasks.foo.ports[0]: host port 8080 is already bound by task "bar"`,
+		`testdata/invalid.yaml:13:15: error: tasks.foo.volumeMounts[0].name: volume "nope" is not defined`,
+		`testdata/invalid.yaml:15:16: error: tasks.foo.semaphore: semaphore "b" is not defined`,
+		`testdata/invalid.yaml:16:25: error: tasks.foo.dependencies[1]: task "missing" is not defined`,
+		`testdata/invalid.yaml:19:16: error: tasks.bar.manifests: both image and manifests are set, manifests would be ignored`,
+		`testdata/invalid.yaml:21:14: warning: tasks.bar.targets: targets without watch, the task will be skipped whenever the targets exist`,
+		`testdata/invalid.yaml:22:19: error: tasks.bar.dependencies: dependency cycle: bar -> foo -> bar`,
+	}, lines)
+
+	t.Run("Included file", func(t *testing.T) {
+		problems := ValidateFile("testdata/include.yaml")
+		if assert.Len(t, problems, 9) {
+			assert.Equal(t, `testdata/include.yaml:5:33: error: tasks.up.dependencies[1]: task "nope" is not defined`, problems[0].String())
+			assert.Equal(t, `testdata/invalid.yaml:10:9: error: tasks.invalid:foo.sh: both command and sh are set, sh would be ignored`, problems[1].String())
+		}
+	})
+	t.Run("Profile", func(t *testing.T) {
+		problems := ValidateFile("testdata/profile.yaml", "broken")
+		if assert.Len(t, problems, 1) {
+			assert.Equal(t, `testdata/profile.yaml:9:16: error: profiles.broken.tasks.test.ports: json: cannot unmarshal bool into Go value of type string`, problems[0].String())
+		}
+	})
+	t.Run("Variables", func(t *testing.T) {
+		problems := ValidateFile("testdata/variables.yaml")
+		if assert.Len(t, problems, 1) {
+			assert.Equal(t, `testdata/variables.yaml:3:12: error: tasks.foo.image: KIT_TEST_IMAGE: image is required`, problems[0].String())
+		}
+	})
+	t.Run("Missing file", func(t *testing.T) {
+		problems := ValidateFile("testdata/missing.yaml")
+		if assert.Len(t, problems, 1) {
+			assert.Equal(t, "error", problems[0].Severity)
+		}
+	})
+}
+
+func TestWriteProblems(t *testing.T) {
+	problems := []Problem{{File: "tasks.yaml", Line: 1, Column: 2, Severity: "error", Path: "tasks.foo", Message: "bad"}}
+	t.Run("Text", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		assert.NoError(t, WriteProblems(buf, problems, "text"))
+		assert.Equal(t, "tasks.yaml:1:2: error: tasks.foo: bad\n", buf.String())
+	})
+	t.Run("JSON", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		assert.NoError(t, WriteProblems(buf, problems, "json"))
+		assert.JSONEq(t, `[{"file":"tasks.yaml","line":1,"column":2,"severity":"error","path":"tasks.foo","message":"bad"}]`, buf.String())
+	})
+	t.Run("No problems", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		assert.NoError(t, WriteProblems(buf, nil, "json"))
+		assert.Equal(t, "[]\n", buf.String())
+	})
+}
@@ -30,6 +30,9 @@ func main() {
 	port := 0
 	openBrowser := false
 	rewrite := false
+	output := ""
+	profiles := ""
+	force := false
 
 	flag.BoolVar(&help, "h", false, "print help and exit")
 	flag.BoolVar(&printVersion, "v", false, "print version and exit")
@@ -38,6 +41,9 @@ func main() {
 	flag.IntVar(&port, "p", 3000, "port to start UI on (default 3000, zero disables)")
 	flag.BoolVar(&openBrowser, "b", false, "open the UI in the browser (default false)")
 	flag.BoolVar(&rewrite, "w", false, "rewrite the config file")
+	flag.StringVar(&output, "o", "", "with -w, write the effective workflow (includes merged, profiles applied, templates merged, variables expanded) to this file instead, - for stdout")
+	flag.StringVar(&profiles, "P", "", "profiles to apply (comma separated)")
+	flag.BoolVar(&force, "force", false, "run tasks even if they are up to date")
 	flag.Parse()
 	taskNames := flag.Args()
 
@@ -54,17 +60,40 @@ func main() {
 
 	err := func() error {
 
+		// split the profiles on comma, but don't end up with a single entry of ""
+		var profileNames []string
+		if profiles != "" {
+			profileNames = strings.Split(profiles, ",")
+		}
+
+		// a task named validate is run, rather than validating the workflow
+		if flag.Arg(0) == "validate" && !hasTask(configFile, profileNames, "validate") {
+			return validate(configFile, profileNames, flag.Args()[1:])
+		}
+
 		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
 		defer cancel()
 
-		wf := &types.Workflow{}
+		if rewrite && output == "" {
+			// rewrite the file as written, we do not want to inline the included files
+			wf, err := types.Read(configFile)
+			if err != nil {
+				return err
+			}
+			out, err := yaml.Marshal(wf)
+			if err != nil {
+				return fmt.Errorf("failed to marshal %s: %w", configFile, err)
+			}
+			return os.WriteFile(configFile, out, 0644)
+		}
 
-		in, err := os.ReadFile(configFile)
+		wf, err := types.Load(configFile)
 		if err != nil {
-			return fmt.Errorf("failed to read %s: %w", configFile, err)
+			return err
 		}
-		if err = yaml.UnmarshalStrict(in, wf); err != nil {
-			return fmt.Errorf("failed to parse %s: %w", configFile, err)
+		wf, err = wf.Effective(profileNames...)
+		if err != nil {
+			return err
 		}
 
 		if rewrite {
@@ -72,7 +101,11 @@ func main() {
 			if err != nil {
 				return fmt.Errorf("failed to marshal %s: %w", configFile, err)
 			}
-			return os.WriteFile(configFile, out, 0644)
+			if output == "-" {
+				_, err = os.Stdout.Write(out)
+				return err
+			}
+			return os.WriteFile(output, out, 0644)
 		}
 
 		// split the tasks on comma, but don't end up with a single entry of ""
@@ -84,12 +117,16 @@ func main() {
 		return internal.RunSubgraph(
 			ctx,
 			cancel,
-			port,
-			openBrowser,
 			log.Default(),
 			wf,
-			taskNames,
-			split,
+			// a matrix task is run as each of its cells
+			wf.Resolve(taskNames),
+			internal.RunOptions{
+				Port:        port,
+				OpenBrowser: openBrowser,
+				TasksToSkip: wf.Resolve(split),
+				Force:       force,
+			},
 		)
 	}()
 
@@ -98,3 +135,45 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// hasTask returns true if the workflow can be loaded and has the task, or a matrix task, with the name
+func hasTask(configFile string, profiles []string, name string) bool {
+	wf, err := types.Load(configFile)
+	if err != nil {
+		return false
+	}
+	wf, err = wf.Effective(profiles...)
+	if err != nil {
+		return false
+	}
+	cells := wf.Resolve([]string{name})
+	_, ok := wf.Tasks[cells[0]]
+	return ok
+}
+
+// validate checks the config file and prints any problems, it returns an error if any of them are errors
+func validate(configFile string, profiles []string, args []string) error {
+	format := ""
+
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	flags.StringVar(&format, "o", "text", "output format, text or json (default text)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	problems := internal.ValidateFile(configFile, profiles...)
+	if err := internal.WriteProblems(os.Stdout, problems, format); err != nil {
+		return err
+	}
+
+	errs := 0
+	for _, p := range problems {
+		if p.Severity == "error" {
+			errs++
+		}
+	}
+	if errs > 0 {
+		return fmt.Errorf("%s has %d error(s)", configFile, errs)
+	}
+	return nil
+}